@@ -0,0 +1,104 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// azureDevOps is a Matcher that represents a single Azure DevOps organization
+// and (optionally) project.
+type azureDevOps struct {
+	host, org, project string
+}
+
+// azureDevOpsGoSource returns a go-source meta-tag for the given repository and
+// go get URL. Azure DevOps selects the branch (GB prefix) or tag (GT prefix)
+// using a query parameter, so the kind of the chosen ref (see Repo.Ref) is
+// used, or a branch if none was chosen yet.
+func azureDevOpsGoSource(r *Repo, u *url.URL) string {
+	azURL := webURL(r)
+	version := "GB" + refName(r)
+	if strings.HasPrefix(r.Ref, "refs/tags/") {
+		version = "GT" + refName(r)
+	}
+	dirPath := "{/dir}"
+	if len(r.Subdir) > 0 {
+		dirPath = "/" + r.Subdir + dirPath
+	}
+
+	// Build a directory-view URL like so:
+	//
+	//  https://dev.azure.com/org/project/_git/pkg?path={/dir}&version=GBv2
+	//
+	dir := azURL.String() + "?path=" + dirPath + "&version=" + version
+
+	// Build a file-view URL like so:
+	//
+	//  https://dev.azure.com/org/project/_git/pkg?path={/dir}/{file}&version=GBv2&line={line}
+	//
+	file := azURL.String() + "?path=" + dirPath + "/{file}&version=" + version + "&line={line}"
+
+	return goSource(r, u, dir, file)
+}
+
+// Match implements the Matcher interface.
+func (a azureDevOps) Match(u *url.URL) (repo *Repo, err error) {
+	p, err := splitPkgPath(u)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a fixed project, the first path element names the project.
+	project, dir := a.project, p.dir
+	if len(project) == 0 {
+		if len(dir) == 0 {
+			return nil, ErrNotPackageURL
+		}
+		project, dir = dir[0], dir[1:]
+	}
+
+	// As with GitHub, the remaining path up to the versioned element is part
+	// of the repository name, with slashes replaced by dashes.
+	repoName := strings.Join(append(dir, p.name), "-")
+	repo = &Repo{
+		Version: p.version,
+		SubPath: p.subPath,
+		URL: &url.URL{
+			Scheme: u.Scheme,
+			Host:   a.host,
+			Path:   path.Join(a.org, project, "_git", repoName),
+		},
+	}
+
+	// Attach the go-source meta-tag, it is rebuilt by the Handler once the
+	// ref is chosen. Azure DevOps repository URLs have no .git suffix.
+	repo.GoSource = azureDevOpsGoSource(repo, u)
+	repo.GoSourceFunc = func(r *Repo) string {
+		return azureDevOpsGoSource(r, u)
+	}
+	return
+}
+
+// AzureDevOps returns a URL Matcher that operates on the Git repositories of a
+// single Azure DevOps organization and project. If the project string is empty,
+// the first path element names the project instead. For instance if the
+// service was running at example.com and the organization string was "bob", it
+// would match URLS in the pattern of:
+//
+//  project "tools":
+//   example.com/pkg.v3 → dev.azure.com/bob/tools/_git/pkg (branch/tag v3, v3.N, or v3.N.M)
+//   example.com/folder/pkg.v3 → dev.azure.com/bob/tools/_git/folder-pkg (branch/tag v3, v3.N, or v3.N.M)
+//
+//  no project:
+//   example.com/tools/pkg.v3 → dev.azure.com/bob/tools/_git/pkg (branch/tag v3, v3.N, or v3.N.M)
+//   example.com/tools/pkg.v3/subpkg → dev.azure.com/bob/tools/_git/pkg (branch/tag v3, v3.N, or v3.N.M)
+//
+// The go-source meta tag links to the branch or tag that was chosen.
+func AzureDevOps(org, project string) Matcher {
+	return azureDevOps{"dev.azure.com", org, project}
+}
@@ -0,0 +1,82 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/url"
+	"testing"
+)
+
+var azureDevOpsTests = []struct {
+	project, url, path, subpath string
+	valid                       bool
+}{
+	{"tools", "pkg.v3", "bob/tools/_git/pkg", "", true},
+	{"tools", "folder/pkg.v3/subpkg", "bob/tools/_git/folder-pkg", "subpkg", true},
+	{"", "tools/pkg.v3", "bob/tools/_git/pkg", "", true},
+	{"", "tools/folder/pkg.v3/subpkg", "bob/tools/_git/folder-pkg", "subpkg", true},
+	{"", "pkg.v3", "", "", false},
+	{"tools", "a.v3/b/c.v3", "", "", false},
+}
+
+// Tests the Azure DevOps URL matcher.
+func TestAzureDevOps(t *testing.T) {
+	for _, tst := range azureDevOpsTests {
+		u, err := url.Parse(tst.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo, err := AzureDevOps("bob", tst.project).Match(u)
+		if tst.valid && err != nil {
+			t.Log(u)
+			t.Fatal("Test is valid but matcher returned:", err)
+		} else if !tst.valid && err == nil {
+			t.Log(u)
+			t.Fatal("Test is invalid but matcher returned nil error!")
+		}
+		if !tst.valid {
+			continue
+		}
+		if repo.URL.Host != "dev.azure.com" || repo.URL.Path != tst.path || repo.SubPath != tst.subpath {
+			t.Log(u)
+			t.Logf("want %q %q\n", tst.path, tst.subpath)
+			t.Logf("got %q %q %q\n", repo.URL.Host, repo.URL.Path, repo.SubPath)
+			t.Fatal("incorrect repo")
+		}
+	}
+}
+
+// Tests the go-source meta tag of the Azure DevOps URL matcher.
+func TestAzureDevOpsGoSource(t *testing.T) {
+	u, err := url.Parse("https://example.com/pkg.v2/subpkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tst := range []struct {
+		ref, version string
+	}{
+		{"", "GBv2"},
+		{"refs/heads/v2", "GBv2"},
+		{"refs/tags/v2.1.0", "GTv2.1.0"},
+	} {
+		repo, err := AzureDevOps("bob", "tools").Match(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tst.ref) > 0 {
+			repo.Ref = tst.ref
+			repo.GoSource = repo.GoSourceFunc(repo)
+		}
+		want := "example.com/pkg.v2 _ " +
+			"https://dev.azure.com/bob/tools/_git/pkg?path={/dir}&version=" + tst.version + " " +
+			"https://dev.azure.com/bob/tools/_git/pkg?path={/dir}/{file}&version=" + tst.version + "&line={line}"
+		if repo.GoSource != want {
+			t.Log(tst.ref)
+			t.Log("want", want)
+			t.Log("got", repo.GoSource)
+			t.Fatal("incorrect go-source")
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/url"
+	"path"
+)
+
+// bitbucket is a Matcher that represents a single Bitbucket workspace, or a
+// single project of a Bitbucket Server instance.
+type bitbucket struct {
+	host, workspace string
+	server          bool
+}
+
+// bitbucketGoSource returns a go-source meta-tag for the given repository and
+// go get URL.
+func bitbucketGoSource(r *Repo, u *url.URL) string {
+	bbURL := webURL(r)
+	ref := sourceRef(r)
+
+	// Build a directory-view URL like so:
+	//
+	//  https://bitbucket.org/workspace/pkg/src/v2{/dir}
+	//
+	dirURL := bbURL
+	dirURL.Path = path.Join(dirURL.Path, "src", ref)
+	dir := dirURL.String() + "{/dir}"
+
+	// Build a file-view URL like so:
+	//
+	//  https://bitbucket.org/workspace/pkg/src/v2{/dir}/{file}#lines-{line}
+	//
+	file := dirURL.String() + "{/dir}/{file}#lines-{line}"
+
+	return goSource(r, u, dir, file)
+}
+
+// bitbucketServerGoSource returns a go-source meta-tag for the given Bitbucket
+// Server repository and go get URL.
+func bitbucketServerGoSource(r *Repo, u *url.URL) string {
+	// The repository URL path is like "scm/KEY/pkg", the web URLs are
+	// instead under "/projects/KEY/repos/pkg".
+	web := webURL(r)
+	name := path.Base(web.Path)
+	project := path.Base(path.Dir(web.Path))
+	at := "?at=" + url.QueryEscape(refName(r))
+
+	// Build a directory-view URL like so:
+	//
+	//  https://git.example.com/projects/KEY/repos/pkg/browse{/dir}?at=v2
+	//
+	web.Path = path.Join("/projects", project, "repos", name, "browse")
+	dir := web.String() + "{/dir}" + at
+
+	// Build a file-view URL like so:
+	//
+	//  https://git.example.com/projects/KEY/repos/pkg/browse{/dir}/{file}?at=v2#{line}
+	//
+	file := web.String() + "{/dir}/{file}" + at + "#{line}"
+
+	return goSource(r, u, dir, file)
+}
+
+// Match implements the Matcher interface.
+func (b bitbucket) Match(u *url.URL) (repo *Repo, err error) {
+	if b.server {
+		// Bitbucket Server repositories are cloned from under "/scm".
+		repo, err = matchDashed(u, b.host, path.Join("scm", b.workspace), bitbucketServerGoSource)
+	} else {
+		repo, err = matchDashed(u, b.host, b.workspace, bitbucketGoSource)
+	}
+	if err != nil {
+		return nil, err
+	}
+	repo.URL.Path += ".git"
+	return
+}
+
+// Bitbucket returns a URL Matcher that operates on a single Bitbucket
+// workspace. Import paths are mapped to repositories as with GitHub, for
+// instance if the service was running at example.com and the workspace string
+// was "bob", it would match URLS in the pattern of:
+//
+//  example.com/pkg.v3 → bitbucket.org/bob/pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/folder/pkg.v3 → bitbucket.org/bob/folder-pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/pkg.v3/folder/subpkg → bitbucket.org/bob/pkg (branch/tag v3, v3.N, or v3.N.M)
+//
+func Bitbucket(workspace string) Matcher {
+	return bitbucket{host: "bitbucket.org", workspace: workspace}
+}
+
+// BitbucketCustomHost is like Bitbucket, but operates on a project of a
+// Bitbucket Server or Data Center instance at the given host, given by its key.
+// For instance if the service was running at example.com, the host was
+// git.example.com and the project key was "BOB", it would match URLS in the
+// pattern of:
+//
+//  example.com/pkg.v3 → git.example.com/scm/BOB/pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/folder/pkg.v3 → git.example.com/scm/BOB/folder-pkg (branch/tag v3, v3.N, or v3.N.M)
+//
+// And the go-source meta tag links to the source browser of the repository,
+// e.g. "https://git.example.com/projects/BOB/repos/pkg/browse?at=v3".
+func BitbucketCustomHost(host, project string) Matcher {
+	return bitbucket{host: host, workspace: project, server: true}
+}
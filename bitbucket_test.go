@@ -0,0 +1,82 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/url"
+	"testing"
+)
+
+var bitbucketTests = []struct {
+	url, host, path, subpath, goSource string
+}{
+	{
+		"https://example.com/pkg.v3",
+		"bitbucket.org", "bob/pkg.git", "",
+		"example.com/pkg.v3 _ " +
+			"https://bitbucket.org/bob/pkg/src/v3{/dir} " +
+			"https://bitbucket.org/bob/pkg/src/v3{/dir}/{file}#lines-{line}",
+	},
+	{
+		"https://example.com/folder/pkg.v1-unstable/subpkg",
+		"bitbucket.org", "bob/folder-pkg.git", "subpkg",
+		"example.com/folder/pkg.v1-unstable _ " +
+			"https://bitbucket.org/bob/folder-pkg/src/v1-unstable{/dir} " +
+			"https://bitbucket.org/bob/folder-pkg/src/v1-unstable{/dir}/{file}#lines-{line}",
+	},
+}
+
+// Tests the Bitbucket URL matcher.
+func TestBitbucket(t *testing.T) {
+	matcher := Bitbucket("bob")
+	for _, tst := range bitbucketTests {
+		u, err := url.Parse(tst.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo, err := matcher.Match(u)
+		if err != nil {
+			t.Log(u)
+			t.Fatal("Test is valid but matcher returned:", err)
+		}
+		if repo.URL.Host != tst.host || repo.URL.Path != tst.path || repo.SubPath != tst.subpath {
+			t.Log(u)
+			t.Logf("want %q %q %q\n", tst.host, tst.path, tst.subpath)
+			t.Logf("got %q %q %q\n", repo.URL.Host, repo.URL.Path, repo.SubPath)
+			t.Fatal("incorrect repo")
+		}
+		if repo.GoSource != tst.goSource {
+			t.Log(u)
+			t.Log("want", tst.goSource)
+			t.Log("got", repo.GoSource)
+			t.Fatal("incorrect go-source")
+		}
+	}
+
+	// Bitbucket Server.
+	u, _ := url.Parse("https://example.com/folder/pkg.v3/subpkg")
+	repo, err := BitbucketCustomHost("git.example.com", "BOB").Match(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.URL.Host != "git.example.com" || repo.URL.Path != "scm/BOB/folder-pkg.git" || repo.SubPath != "subpkg" {
+		t.Fatalf("got %q %q %q\n", repo.URL.Host, repo.URL.Path, repo.SubPath)
+	}
+	repo.Ref = "refs/tags/v3.1.0"
+	want := "example.com/folder/pkg.v3 _ " +
+		"https://git.example.com/projects/BOB/repos/folder-pkg/browse{/dir}?at=v3.1.0 " +
+		"https://git.example.com/projects/BOB/repos/folder-pkg/browse{/dir}/{file}?at=v3.1.0#{line}"
+	if got := repo.GoSourceFunc(repo); got != want {
+		t.Log("want", want)
+		t.Log("got", got)
+		t.Fatal("incorrect go-source")
+	}
+
+	// Paths are rejected just like with GitHub.
+	u, _ = url.Parse("a.v3/b/c.v3")
+	if _, err := BitbucketCustomHost("git.example.com", "bob").Match(u); err != ErrNotPackageURL {
+		t.Fatal("want ErrNotPackageURL, got", err)
+	}
+}
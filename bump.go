@@ -0,0 +1,170 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// IncMajor returns the next major version. Present minor and patch version
+// numbers are reset to zero, absent ones stay absent, and any pre-release,
+// unstable status and build metadata is dropped:
+//
+//  v1           -> v2
+//  v1.2         -> v2.0
+//  v1.2.3       -> v2.0.0
+//  v1.2.3-rc.1  -> v2.0.0
+//  v0.3.1       -> v1.0.0
+//
+// A pre-release of a major version (i.e. with zero or absent minor and patch
+// version numbers) is finalised instead, like npm and Cargo do:
+//
+//  v2.0.0-rc.1  -> v2.0.0
+//  v2-unstable  -> v2
+//
+// v0 is not treated specially, it is simply incremented to v1. The next
+// version of an invalid version is InvalidVersion.
+func (v Version) IncMajor() Version {
+	if v.Precision() == 0 {
+		return InvalidVersion
+	}
+	next := v.Release()
+	if len(v.preRelease()) > 0 && precisionZero(v.Minor) == 0 && precisionZero(v.Patch) == 0 {
+		return next
+	}
+	next.Major++
+	if next.Minor >= 0 {
+		next.Minor = 0
+	}
+	if next.Patch >= 0 {
+		next.Patch = 0
+	}
+	return next
+}
+
+// IncMinor returns the next minor version. An absent minor version number is
+// treated as zero, a present patch version number is reset to zero, and any
+// pre-release, unstable status and build metadata is dropped:
+//
+//  v1           -> v1.1
+//  v1.2         -> v1.3
+//  v1.2.3       -> v1.3.0
+//  v1.2.3-rc.1  -> v1.3.0
+//  v0           -> v0.1
+//
+// A pre-release of a minor version (i.e. with a zero or absent patch version
+// number) is finalised instead, like npm and Cargo do:
+//
+//  v2.1.0-rc.1  -> v2.1.0
+//  v2-unstable  -> v2
+//
+// The major version number is never changed, even for v0. The next version of
+// an invalid version is InvalidVersion.
+func (v Version) IncMinor() Version {
+	if v.Precision() == 0 {
+		return InvalidVersion
+	}
+	next := v.Release()
+	if len(v.preRelease()) > 0 && precisionZero(v.Patch) == 0 {
+		return next
+	}
+	next.Minor = precisionZero(next.Minor) + 1
+	if next.Patch >= 0 {
+		next.Patch = 0
+	}
+	return next
+}
+
+// IncPatch returns the next patch version. Absent minor and patch version
+// numbers are treated as zero, and any build metadata is dropped:
+//
+//  v1           -> v1.0.1
+//  v1.2         -> v1.2.1
+//  v1.2.3       -> v1.2.4
+//  v0           -> v0.0.1
+//
+// A pre-release or unstable version is finalised instead, like npm and Cargo
+// do, see Release:
+//
+//  v1.2.3-rc.1  -> v1.2.3
+//  v2-unstable  -> v2
+//
+// The major version number is never changed, even for v0. The next version of
+// an invalid version is InvalidVersion.
+func (v Version) IncPatch() Version {
+	if v.Precision() == 0 {
+		return InvalidVersion
+	}
+	next := v.Release()
+	if len(v.preRelease()) > 0 {
+		return next
+	}
+	next.Minor = precisionZero(next.Minor)
+	next.Patch = precisionZero(next.Patch) + 1
+	return next
+}
+
+// Release returns the release version of an unstable or pre-release version,
+// that is the same version without the unstable status, pre-release and build
+// metadata:
+//
+//  v1-unstable     -> v1
+//  v1.2.3-rc.1     -> v1.2.3
+//  v1.2.3+build.5  -> v1.2.3
+//  v1.2.3          -> v1.2.3
+//
+// Absent version numbers stay absent, and v0 is not treated specially.
+func (v Version) Release() Version {
+	v.Unstable = false
+	v.PreRelease = ""
+	v.Metadata = ""
+	return v
+}
+
+// IncPreRelease returns the next pre-release version. If the last pre-release
+// identifier is numeric it is incremented, otherwise a ".1" identifier is
+// appended. The unstable status is treated as the pre-release "unstable". Any
+// build metadata is dropped:
+//
+//  v2.1.0-rc.1  -> v2.1.0-rc.2
+//  v2.1.0-beta  -> v2.1.0-beta.1
+//  v2.1.0-0     -> v2.1.0-1
+//  v2-unstable  -> v2-unstable.1
+//
+// A release version is first incremented with IncPatch, and then given the
+// pre-release "0" (such that it sorts before any other pre-release):
+//
+//  v1.2.3  -> v1.2.4-0
+//  v1      -> v1.0.1-0
+//  v0      -> v0.0.1-0
+//
+// The next version of an invalid version is InvalidVersion.
+func (v Version) IncPreRelease() Version {
+	if v.Precision() == 0 {
+		return InvalidVersion
+	}
+	pre := v.PreRelease
+	if v.Unstable {
+		pre = "unstable"
+	}
+	if len(pre) == 0 {
+		next := v.IncPatch()
+		next.PreRelease = "0"
+		return next
+	}
+
+	next := v.Release()
+	ids := strings.Split(pre, ".")
+	last := ids[len(ids)-1]
+	if n, err := strconv.Atoi(last); err == nil && isNumeric(last) && n < math.MaxInt {
+		ids[len(ids)-1] = strconv.Itoa(n + 1)
+	} else {
+		ids = append(ids, "1")
+	}
+	next.PreRelease = strings.Join(ids, ".")
+	return next
+}
@@ -0,0 +1,52 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import "testing"
+
+var versionBumpTests = []struct {
+	v, major, minor, patch, release, pre string
+}{
+	{"v1", "v2", "v1.1", "v1.0.1", "v1", "v1.0.1-0"},
+	{"v1.2", "v2.0", "v1.3", "v1.2.1", "v1.2", "v1.2.1-0"},
+	{"v1.2.3", "v2.0.0", "v1.3.0", "v1.2.4", "v1.2.3", "v1.2.4-0"},
+	{"v0", "v1", "v0.1", "v0.0.1", "v0", "v0.0.1-0"},
+	{"v0.3.1", "v1.0.0", "v0.4.0", "v0.3.2", "v0.3.1", "v0.3.2-0"},
+	{"v1.2.3+build.5", "v2.0.0", "v1.3.0", "v1.2.4", "v1.2.3", "v1.2.4-0"},
+	{"v2.1.0-rc.1", "v3.0.0", "v2.1.0", "v2.1.0", "v2.1.0", "v2.1.0-rc.2"},
+	{"v2.1.0-rc.9+build.5", "v3.0.0", "v2.1.0", "v2.1.0", "v2.1.0", "v2.1.0-rc.10"},
+	{"v2.1.0-beta", "v3.0.0", "v2.1.0", "v2.1.0", "v2.1.0", "v2.1.0-beta.1"},
+	{"v2.1.0-0", "v3.0.0", "v2.1.0", "v2.1.0", "v2.1.0", "v2.1.0-1"},
+	{"v2.1.3-rc.1", "v3.0.0", "v2.2.0", "v2.1.3", "v2.1.3", "v2.1.3-rc.2"},
+	{"v2.0.0-rc.1", "v2.0.0", "v2.0.0", "v2.0.0", "v2.0.0", "v2.0.0-rc.2"},
+	{"v2.1-rc.1", "v3.0", "v2.1", "v2.1", "v2.1", "v2.1-rc.2"},
+	{"v2-unstable", "v2", "v2", "v2", "v2", "v2-unstable.1"},
+}
+
+func TestVersionBump(t *testing.T) {
+	for _, tst := range versionBumpTests {
+		v := ParseVersion(tst.v)
+		for _, c := range []struct {
+			name string
+			got  Version
+			want string
+		}{
+			{"IncMajor", v.IncMajor(), tst.major},
+			{"IncMinor", v.IncMinor(), tst.minor},
+			{"IncPatch", v.IncPatch(), tst.patch},
+			{"Release", v.Release(), tst.release},
+			{"IncPreRelease", v.IncPreRelease(), tst.pre},
+		} {
+			if c.got != ParseVersion(c.want) {
+				t.Logf("%q\n", tst.v)
+				t.Fatalf("%s got %q want %q\n", c.name, c.got, c.want)
+			}
+		}
+	}
+
+	if InvalidVersion.IncMajor() != InvalidVersion || InvalidVersion.IncPreRelease() != InvalidVersion {
+		t.Fatal("next version of InvalidVersion must be InvalidVersion")
+	}
+}
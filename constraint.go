@@ -0,0 +1,382 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint represents a parsed version constraint expression, for example:
+//
+//  "^1.2"              (>=1.2.0 <2.0.0)
+//  "~1.4.0"            (>=1.4.0 <1.5.0)
+//  ">=1.2 <2.0"        (>=1.2.0 <2.0.0)
+//  ">=1.2 <2.0 || 3.x" (>=1.2.0 <2.0.0, or >=3.0.0 <4.0.0)
+//
+// See ParseConstraint for the full syntax.
+type Constraint struct {
+	str string
+
+	// The sets of comparators, a version satisfies the constraint if it
+	// satisfies all comparators of any single set.
+	sets [][]comparator
+}
+
+// comparator is a single comparison against a version, e.g. ">=1.2.0".
+type comparator struct {
+	op string // One of "=", "<", "<=", ">", ">=".
+	v  Version
+}
+
+// check tells if v satisfies the comparator.
+func (c comparator) check(v Version) bool {
+	cmp := comparePrecedence(v, c.v)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// String returns the original constraint expression string.
+func (c *Constraint) String() string {
+	return c.str
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. The text form is
+// the original constraint expression string.
+func (c *Constraint) MarshalText() ([]byte, error) {
+	return []byte(c.str), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The text is
+// parsed using ParseConstraint, and any *ParseError is returned as-is.
+func (c *Constraint) UnmarshalText(text []byte) error {
+	parsed, err := ParseConstraint(string(text))
+	if err != nil {
+		return err
+	}
+	*c = *parsed
+	return nil
+}
+
+// Check tells if the given version satisfies the constraint.
+//
+// Pre-release and unstable versions (e.g. v1.3.0-rc.1) only satisfy the
+// constraint if it explicitly mentions a pre-release of the same major, minor
+// and patch version (e.g. ">=1.3.0-rc.0"). This prevents e.g. "^1.2" from
+// matching pre-releases that users have not opted into.
+//
+// Build metadata is ignored, and InvalidVersion never satisfies a constraint.
+func (c *Constraint) Check(v Version) bool {
+	if v.Major < 0 {
+		return false
+	}
+	for _, set := range c.sets {
+		if checkSet(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSet tells if v satisfies every comparator in the set.
+func checkSet(set []comparator, v Version) bool {
+	for _, c := range set {
+		if !c.check(v) {
+			return false
+		}
+	}
+	if !v.Unstable && len(v.PreRelease) == 0 {
+		return true
+	}
+
+	// Pre-release versions must be explicitly allowed by a comparator in the
+	// set having a pre-release of the same version.
+	for _, c := range set {
+		if !c.v.Unstable && len(c.v.PreRelease) == 0 {
+			continue
+		}
+		if c.v.Major == v.Major && c.v.Minor == precisionZero(v.Minor) && c.v.Patch == precisionZero(v.Patch) {
+			return true
+		}
+	}
+	return false
+}
+
+// Best returns the greatest version in the list satisfying the constraint, as
+// compared by the given version scheme. It is the version a Handler using the
+// scheme chooses for a Repo with the constraint; for the default scheme of a
+// Handler, use SemVer{Ordering: h.Ordering}. If no version satisfies the
+// constraint, ok=false is returned.
+func (c *Constraint) Best(versions []Version, s VersionScheme) (best Version, ok bool) {
+	for _, v := range versions {
+		if !c.Check(v) {
+			continue
+		}
+		if !ok || s.Compare(best, v) < 0 {
+			best = v
+			ok = true
+		}
+	}
+	return
+}
+
+// ParseConstraint parses a version constraint expression. An expression is a
+// list of comparator sets separated by "||", where each set is a list of
+// whitespace-separated comparators that must all be satisfied. A comparator is
+// an optional operator followed by a (possibly partial) version, with or
+// without the "v" prefix:
+//
+//  "1.2.3", "=1.2.3"  exactly 1.2.3
+//  "1.2", "1.2.x"     >=1.2.0 <1.3.0
+//  "1", "1.x", "1.*"  >=1.0.0 <2.0.0
+//  "*", "x"           any version
+//  ">1.2", ">=1.2"    >=1.3.0, >=1.2.0
+//  "<1.2", "<=1.2"    <1.2.0, <1.3.0
+//  "~1.4.0", "~1.4"   >=1.4.0 <1.5.0
+//  "~1"               >=1.0.0 <2.0.0
+//  "^1.2.3", "^1.2"   >=1.2.3 <2.0.0, >=1.2.0 <2.0.0
+//  "^0.2.3"           >=0.2.3 <0.3.0
+//  "^0.0.3"           >=0.0.3 <0.0.4
+//
+// Absent version components are treated as zero, e.g. v1.2 satisfies "=1.2.0".
+//
+// Any error returned is a *ParseError describing the problem.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{str: s}
+	setStart := 0
+	for _, setStr := range strings.Split(s, "||") {
+		fields, offsets := splitFields(setStr)
+		if len(fields) == 0 {
+			return nil, &ParseError{Input: s, Pos: setStart, Msg: "empty constraint"}
+		}
+		var set []comparator
+		for i := 0; i < len(fields); i++ {
+			// Split the operator from the version. We allow whitespace
+			// between the two, as in ">= 1.2".
+			opLen := len(fields[i]) - len(strings.TrimLeft(fields[i], "^~=<>"))
+			op, opPos := fields[i][:opLen], offsets[i]
+			ver, verPos := fields[i][opLen:], offsets[i]+opLen
+			if len(ver) == 0 && i+1 < len(fields) {
+				i++
+				ver, verPos = fields[i], offsets[i]
+			}
+			comps, err := parseComparator(op, ver)
+			if err != nil {
+				err.Input = s
+				if err.Pos == -1 {
+					err.Pos = setStart + opPos
+				} else {
+					err.Pos += setStart + verPos
+				}
+				return nil, err
+			}
+			set = append(set, comps...)
+		}
+		c.sets = append(c.sets, set)
+		setStart += len(setStr) + len("||")
+	}
+	return c, nil
+}
+
+// splitFields is like strings.Fields, but also returns the byte offset of each
+// field in s.
+func splitFields(s string) (fields []string, offsets []int) {
+	start := -1
+	for i := 0; i <= len(s); i++ {
+		space := i == len(s) || s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r'
+		if space && start != -1 {
+			fields = append(fields, s[start:i])
+			offsets = append(offsets, start)
+			start = -1
+		} else if !space && start == -1 {
+			start = i
+		}
+	}
+	return
+}
+
+// MustParseConstraint is like ParseConstraint, but panics if the expression
+// cannot be parsed. It is intended for use in variable initializations.
+func MustParseConstraint(s string) *Constraint {
+	c, err := ParseConstraint(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// partialVersion is a possibly-partial version inside of a constraint, like
+// "1", "1.2.x" or "1.2.3-rc.1".
+type partialVersion struct {
+	Version
+
+	// The number of version numbers present (0-3), wildcards and any version
+	// numbers following them are not counted.
+	n int
+}
+
+// bump returns the smallest full version which is greater than every version
+// matching the partial version, e.g. "1.2" -> "1.3.0" and "1" -> "2.0.0".
+func (p partialVersion) bump() Version {
+	v := Version{Major: p.Major, Minor: 0, Patch: 0}
+	if p.n == 1 {
+		v.Major++
+	} else {
+		v.Minor = p.Minor + 1
+	}
+	return v
+}
+
+// parseComparator parses a single comparator term, like ">=1.2" or "^1.2.3",
+// split into its operator and version, into the equivalent list of simple
+// comparators. The returned error's position is relative to the version
+// string, or -1 for an invalid operator.
+func parseComparator(op, ver string) ([]comparator, *ParseError) {
+	switch op {
+	case "", "=", "<", "<=", ">", ">=", "~", "^":
+	default:
+		return nil, &ParseError{Pos: -1, Msg: fmt.Sprintf("unknown operator %q", op)}
+	}
+	p, err := parsePartialVersion(ver)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		all  = []comparator{{">=", Version{}}}
+		none = []comparator{{"<", Version{}}}
+	)
+	if p.n == 0 {
+		// A wildcard like "*" or "x".
+		switch op {
+		case "<", ">":
+			return none, nil
+		}
+		return all, nil
+	}
+	if p.n == 3 {
+		switch op {
+		case "", "=":
+			return []comparator{{"=", p.Version}}, nil
+		case "<", "<=", ">", ">=":
+			return []comparator{{op, p.Version}}, nil
+		}
+	}
+
+	// Fill absent components with zeros.
+	lower := p.Version
+	if p.n < 3 {
+		lower.Patch = 0
+	}
+	if p.n < 2 {
+		lower.Minor = 0
+	}
+	switch op {
+	case "", "=":
+		return []comparator{{">=", lower}, {"<", p.bump()}}, nil
+	case "<":
+		return []comparator{{"<", lower}}, nil
+	case "<=":
+		return []comparator{{"<", p.bump()}}, nil
+	case ">":
+		return []comparator{{">=", p.bump()}}, nil
+	case ">=":
+		return []comparator{{">=", lower}}, nil
+	case "~":
+		upper := partialVersion{Version: lower, n: p.n}
+		if upper.n > 2 {
+			upper.n = 2
+		}
+		return []comparator{{">=", lower}, {"<", upper.bump()}}, nil
+	}
+
+	// Caret: allow changes that do not modify the left-most non-zero version
+	// number.
+	var upper Version
+	switch {
+	case lower.Major > 0 || p.n == 1:
+		upper = Version{Major: lower.Major + 1, Minor: 0, Patch: 0}
+	case lower.Minor > 0 || p.n == 2:
+		upper = Version{Major: 0, Minor: lower.Minor + 1, Patch: 0}
+	default:
+		upper = Version{Major: 0, Minor: 0, Patch: lower.Patch + 1}
+	}
+	return []comparator{{">=", lower}, {"<", upper}}, nil
+}
+
+// parsePartialVersion parses a possibly-partial version like "1", "v1.2.x" or
+// "1.2.3-rc.1". The returned error's Input is the given string.
+func parsePartialVersion(s string) (partialVersion, *ParseError) {
+	fail := func(pos int, msg string) (partialVersion, *ParseError) {
+		return partialVersion{}, &ParseError{Input: s, Pos: pos, Msg: msg}
+	}
+	if len(s) == 0 {
+		return fail(0, "expected version")
+	}
+	pos := 0
+	if s[0] == 'v' {
+		pos++
+	}
+
+	p := partialVersion{Version: Version{Major: 0, Minor: 0, Patch: 0}}
+	wildcard := false
+	for n := 0; n < 3; n++ {
+		start := pos
+		for pos < len(s) && s[pos] != '.' && s[pos] != '-' && s[pos] != '+' {
+			pos++
+		}
+		num := s[start:pos]
+		switch {
+		case num == "x" || num == "X" || num == "*":
+			wildcard = true
+		case wildcard:
+			return fail(start, "version number after wildcard")
+		case !isNumeric(num):
+			return fail(start, "expected version number or wildcard")
+		case len(num) > 1 && num[0] == '0':
+			return fail(start, "leading zero in version number")
+		default:
+			i, err := strconv.Atoi(num)
+			if err != nil {
+				return fail(start, "version number out of range")
+			}
+			switch n {
+			case 0:
+				p.Major = i
+			case 1:
+				p.Minor = i
+			case 2:
+				p.Patch = i
+			}
+			p.n++
+		}
+		if pos == len(s) || s[pos] != '.' {
+			break
+		}
+		pos++ // Skip the dot.
+	}
+	// Parse the pre-release and build metadata suffixes, the latter is simply
+	// ignored.
+	if i, msg := parseSuffix(s, pos, &p.Version); i != -1 {
+		return fail(i, msg)
+	}
+	p.Metadata = ""
+	if p.n != 3 && (p.Unstable || len(p.PreRelease) > 0) {
+		return fail(pos+1, "pre-release requires a full version")
+	}
+	return p, nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import "testing"
+
+var constraintCheckTests = []struct {
+	constraint string
+	v          string
+	ok         bool
+}{
+	// Exact and partial versions.
+	{"1.2.3", "v1.2.3", true},
+	{"=1.2.3", "v1.2.3+build.1", true},
+	{"1.2.3", "v1.2.4", false},
+	{"1.2", "v1.2.9", true},
+	{"1.2", "v1.2", true},
+	{"1.2", "v1.3.0", false},
+	{"v1", "v1.9.9", true},
+	{"1.x", "v1.9.9", true},
+	{"1.2.*", "v1.2.0", true},
+	{"1.2.*", "v1.3.0", false},
+	{"*", "v0.0.1", true},
+	{"x", "v42", true},
+
+	// Comparison operators.
+	{">1.2", "v1.2.9", false},
+	{">1.2", "v1.3.0", true},
+	{">1.2.3", "v1.2.4", true},
+	{">=1.2", "v1.2.0", true},
+	{">= 1.2", "v1.1.9", false},
+	{"<1.2", "v1.1.9", true},
+	{"<1.2", "v1.2.0", false},
+	{"<=1.2", "v1.2.9", true},
+	{"<=1.2", "v1.3.0", false},
+	{"<=1.2.3", "v1.2.3", true},
+
+	// Tilde ranges.
+	{"~1.4.0", "v1.4.7", true},
+	{"~1.4.0", "v1.5.0", false},
+	{"~1.4.2", "v1.4.1", false},
+	{"~1.4", "v1.4.0", true},
+	{"~1", "v1.9.0", true},
+	{"~1", "v2.0.0", false},
+
+	// Caret ranges.
+	{"^1.2", "v1.2.0", true},
+	{"^1.2", "v1.9.9", true},
+	{"^1.2", "v2.0.0", false},
+	{"^1.2", "v1.1.9", false},
+	{"^1.2.3", "v1.2.2", false},
+	{"^0.2.3", "v0.2.9", true},
+	{"^0.2.3", "v0.3.0", false},
+	{"^0.0.3", "v0.0.3", true},
+	{"^0.0.3", "v0.0.4", false},
+	{"^0", "v0.9.0", true},
+
+	// Sets and alternatives.
+	{">=1.2 <2.0", "v1.9.9", true},
+	{">=1.2 <2.0", "v2.0.0", false},
+	{">=1.2 <2.0 || 3.x", "v3.4.5", true},
+	{">=1.2 <2.0 || 3.x", "v2.5.0", false},
+	{">=1.2 <2.0 || 3.x", "v1.5.0", true},
+
+	// Pre-release and unstable versions must be opted into.
+	{"^1.2", "v1.3.0-rc.1", false},
+	{">=1.3.0-rc.0", "v1.3.0-rc.1", true},
+	{">=1.3.0-rc.0", "v1.3.1-rc.1", false},
+	{">=1.3.0-rc.0", "v1.3.1", true},
+	{"^1.3.0-beta", "v1.3.0-rc.1", true},
+	{"^1", "v1-unstable", false},
+	{"1.2.3-unstable", "v1.2.3-unstable", true},
+
+	// Invalid versions never match.
+	{"*", "v", false},
+}
+
+func TestConstraintCheck(t *testing.T) {
+	for _, tst := range constraintCheckTests {
+		c, err := ParseConstraint(tst.constraint)
+		if err != nil {
+			t.Logf("%q\n", tst.constraint)
+			t.Fatal(err)
+		}
+		if got := c.Check(ParseVersion(tst.v)); got != tst.ok {
+			t.Logf("constraint %q, version %q\n", tst.constraint, tst.v)
+			t.Fatalf("got %t want %t\n", got, tst.ok)
+		}
+	}
+}
+
+var constraintParseErrorTests = []struct {
+	constraint string
+	pos        int
+}{
+	{"", 0},
+	{">=1.2 ||", 8},
+	{"|| 1.2", 0},
+	{"=>1.2", 0},
+	{">=1.2 <2.a", 9},
+	{"1.02", 2},
+	{"1.x.3", 4},
+	{"1.2-rc.1", 4},
+	{"1.2.3-rc..1", 9},
+	{"1.2.3 >=", 8},
+}
+
+func TestConstraintParseError(t *testing.T) {
+	for _, tst := range constraintParseErrorTests {
+		_, err := ParseConstraint(tst.constraint)
+		pErr, ok := err.(*ParseError)
+		if !ok {
+			t.Logf("%q\n", tst.constraint)
+			t.Fatalf("want *ParseError, got %#v\n", err)
+		}
+		if pErr.Pos != tst.pos || pErr.Input != tst.constraint {
+			t.Logf("%q\n", tst.constraint)
+			t.Fatalf("got error %q at %d, want position %d\n", pErr, pErr.Pos, tst.pos)
+		}
+	}
+}
+
+func TestConstraintBest(t *testing.T) {
+	var versions []Version
+	for _, s := range []string{"v1.2.0", "v1.4.1", "v2.0.0", "v1.4.0", "v1.5.0-rc.1", "v3.1.0"} {
+		versions = append(versions, ParseVersion(s))
+	}
+	tests := []struct {
+		constraint, best string
+	}{
+		{"^1.2", "v1.4.1"},
+		{"~1.4.0", "v1.4.1"},
+		{">=1.2 <2.0 || 3.x", "v3.1.0"},
+		{"<1.3", "v1.2.0"},
+		{"4.x", ""},
+	}
+	for _, tst := range tests {
+		best, ok := MustParseConstraint(tst.constraint).Best(versions, SemVer{})
+		if ok != (len(tst.best) > 0) || ok && best != ParseVersion(tst.best) {
+			t.Logf("%q\n", tst.constraint)
+			t.Fatalf("got %v (ok=%t) want %q\n", best, ok, tst.best)
+		}
+	}
+
+	// The versions are compared by the given scheme, as by a Handler.
+	if best, _ := MustParseConstraint("^1.2").Best(versions, majorMinorScheme{}); best != ParseVersion("v1.2.0") {
+		t.Fatalf("got %v want v1.2.0\n", best)
+	}
+	if best, _ := MustParseConstraint("^1.2").Best(parseVersions("v1.2.0 v1.2"), SemVer{}); best != ParseVersion("v1.2.0") {
+		t.Fatalf("got %v want v1.2.0\n", best)
+	}
+}
@@ -58,7 +58,9 @@
 //      // home page).
 //  }
 //
-// The package exposes a matcher only for GitHub. But others can be implemented
-// outside the package as well for e.g. Google Code or privately hosted Git
-// repositories.
+// The package exposes matchers for GitHub, GitLab, Bitbucket, Gitea, SourceHut
+// and Azure DevOps, a TemplateMatcher for other hosts, and a Registry of
+// explicitly mapped repositories. Matchers can be combined using First, Prefix
+// and Rewrite, and others can be implemented outside the package as well for
+// e.g. privately hosted Git repositories.
 package semver // import "azul3d.org/semver.v2"
@@ -0,0 +1,152 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+var errMarshalInvalid = errors.New("cannot marshal invalid version")
+
+// MarshalText implements the encoding.TextMarshaler interface. The text form
+// is the same as String, an error is returned for invalid versions.
+func (v Version) MarshalText() ([]byte, error) {
+	if v.Precision() == 0 {
+		return nil, errMarshalInvalid
+	}
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The text is
+// parsed using ParseVersionStrict, and any *ParseError is returned as-is.
+func (v *Version) UnmarshalText(text []byte) error {
+	parsed, err := ParseVersionStrict(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface. The version is encoded
+// as a JSON string, e.g. "v1.2.3", an error is returned for invalid versions.
+func (v Version) MarshalJSON() ([]byte, error) {
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. The data must be a
+// JSON string which is parsed using ParseVersionStrict, and any *ParseError is
+// returned as-is. As with other types, a JSON null leaves v unmodified.
+func (v *Version) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return v.UnmarshalText([]byte(s))
+}
+
+// Scan implements the database/sql.Scanner interface. A string or []byte
+// value is parsed using ParseVersionStrict, and any *ParseError is returned
+// as-is. A SQL NULL value is scanned as InvalidVersion.
+func (v *Version) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case nil:
+		*v = InvalidVersion
+		return nil
+	case string:
+		return v.UnmarshalText([]byte(s))
+	case []byte:
+		return v.UnmarshalText(s)
+	}
+	return fmt.Errorf("cannot scan %T into version", src)
+}
+
+// Value implements the database/sql/driver.Valuer interface. The version is
+// stored as its string form, except InvalidVersion which is stored as a SQL
+// NULL value. An error is returned for any other invalid version.
+func (v Version) Value() (driver.Value, error) {
+	if v == InvalidVersion {
+		return nil, nil
+	}
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
+
+// repoJSON is the JSON form of a Repo, it has the fields of a Repo but not the
+// methods of the embedded Version.
+type repoJSON struct {
+	Version Version
+	*url.URL
+	SubPath    string
+	GoSource   string
+	Constraint *Constraint
+	Subdir     string
+	Default    DefaultVersion
+	Fallback   *Repo
+	Ref        string
+}
+
+// MarshalJSON implements the json.Marshaler interface. It shadows the method
+// of the embedded Version, such that all fields of the repository are encoded
+// (except GoSourceFunc), e.g.:
+//
+//  {"Version":"v2","Scheme":"https","Host":"github.com", ..., "Ref":""}
+//
+func (r Repo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(repoJSON{
+		Version:    r.Version,
+		URL:        r.URL,
+		SubPath:    r.SubPath,
+		GoSource:   r.GoSource,
+		Constraint: r.Constraint,
+		Subdir:     r.Subdir,
+		Default:    r.Default,
+		Fallback:   r.Fallback,
+		Ref:        r.Ref,
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, it decodes the form
+// encoded by MarshalJSON. Fields missing from the data are left unmodified.
+func (r *Repo) UnmarshalJSON(data []byte) error {
+	j := repoJSON{
+		Version:    r.Version,
+		URL:        r.URL,
+		SubPath:    r.SubPath,
+		GoSource:   r.GoSource,
+		Constraint: r.Constraint,
+		Subdir:     r.Subdir,
+		Default:    r.Default,
+		Fallback:   r.Fallback,
+		Ref:        r.Ref,
+	}
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	r.Version = j.Version
+	r.URL = j.URL
+	r.SubPath = j.SubPath
+	r.GoSource = j.GoSource
+	r.Constraint = j.Constraint
+	r.Subdir = j.Subdir
+	r.Default = j.Default
+	r.Fallback = j.Fallback
+	r.Ref = j.Ref
+	return nil
+}
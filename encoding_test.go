@@ -0,0 +1,216 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestVersionJSON(t *testing.T) {
+	type config struct {
+		Min, Max Version
+		Opt      *Version
+	}
+	in := config{
+		Min: ParseVersion("v1.0"),
+		Max: ParseVersion("v2.1.0-rc.1+build.5"),
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Min":"v1.0","Max":"v2.1.0-rc.1+build.5","Opt":null}`
+	if string(data) != want {
+		t.Fatalf("got %s want %s\n", data, want)
+	}
+
+	var out config
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Fatalf("got %#v want %#v\n", out, in)
+	}
+
+	// Invalid versions must return the strict parsing error.
+	for _, s := range []string{`{"Min":"v01"}`, `{"Min":"1.2"}`, `{"Min":""}`} {
+		err := json.Unmarshal([]byte(s), &out)
+		if _, ok := err.(*ParseError); !ok {
+			t.Logf("%s\n", s)
+			t.Fatalf("want *ParseError, got %#v\n", err)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"Min":12}`), &out); err == nil {
+		t.Fatal("want error for non-string JSON value")
+	}
+	if _, err := json.Marshal(config{Min: InvalidVersion}); err == nil {
+		t.Fatal("want error marshaling InvalidVersion")
+	}
+}
+
+func TestVersionText(t *testing.T) {
+	var v Version
+	if err := v.UnmarshalText([]byte("v1.2.3-unstable")); err != nil {
+		t.Fatal(err)
+	}
+	text, err := v.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "v1.2.3-unstable" {
+		t.Fatalf("got %q want %q\n", text, "v1.2.3-unstable")
+	}
+	if err := v.UnmarshalText([]byte("v1.2.3.4")); err == nil {
+		t.Fatal("want error for trailing garbage")
+	}
+}
+
+// Tests that the text form of every leniently parsed version is accepted by
+// UnmarshalText (e.g. leading zeros are dropped, and pre-release identifiers
+// with leading zeros are never parsed).
+func TestVersionTextLenient(t *testing.T) {
+	for _, tst := range versionParseTests {
+		v := ParseVersion(tst.v)
+		if v == InvalidVersion {
+			continue
+		}
+		text, err := v.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Version
+		if err := got.UnmarshalText(text); err != nil || got != v {
+			t.Fatalf("%q: UnmarshalText(%q) got %v (err=%v) want %v\n", tst.v, text, got, err, v)
+		}
+	}
+	if v := ParseVersion("v1-rc.01"); v != InvalidVersion {
+		t.Fatalf("ParseVersion(%q) got %v want InvalidVersion\n", "v1-rc.01", v)
+	}
+}
+
+func TestVersionSQL(t *testing.T) {
+	var v Version
+	for _, src := range []interface{}{"v1.4.2", []byte("v1.4.2")} {
+		if err := v.Scan(src); err != nil {
+			t.Fatal(err)
+		}
+		if v != ParseVersion("v1.4.2") {
+			t.Fatalf("Scan(%#v) got %#v\n", src, v)
+		}
+	}
+	val, err := v.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != "v1.4.2" {
+		t.Fatalf("Value() got %#v want %q\n", val, "v1.4.2")
+	}
+
+	// NULL round-trips as InvalidVersion.
+	if err := v.Scan(nil); err != nil {
+		t.Fatal(err)
+	}
+	if v != InvalidVersion {
+		t.Fatalf("Scan(nil) got %#v want InvalidVersion\n", v)
+	}
+	val, err = v.Value()
+	if err != nil || val != nil {
+		t.Fatalf("Value() got %#v, %v want nil, nil\n", val, err)
+	}
+
+	if _, ok := v.Scan("v1.x").(*ParseError); !ok {
+		t.Fatal("want *ParseError scanning invalid version")
+	}
+	if err := v.Scan(42); err == nil {
+		t.Fatal("want error scanning int")
+	}
+}
+
+// Tests that a Repo is marshaled as a whole, not just its version.
+func TestRepoJSON(t *testing.T) {
+	u, _ := url.Parse("https://example.com/pkg.v2/sub")
+	repo, err := GitHub("bob").Match(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Constraint = MustParseConstraint("^2.1")
+	data, err := json.Marshal(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"Version":"v2"`,
+		`"Host":"github.com"`,
+		`"Path":"bob/pkg.git"`,
+		`"SubPath":"sub"`,
+		`"GoSource":"example.com/pkg.v2 _ https://github.com/bob/pkg/tree/v2{/dir}`,
+		`"Constraint":"^2.1"`,
+		`"Default":"none"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Log(string(data))
+			t.Fatal("missing", want)
+		}
+	}
+
+	// Repo values are encoded the same way, and decoded back.
+	valueData, err := json.Marshal(*repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(valueData) != string(data) {
+		t.Log(string(valueData))
+		t.Fatal("Repo and *Repo encodings differ")
+	}
+	repo.Default = DefaultBranch("main")
+	data, err = json.Marshal(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Repo
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != repo.Version || got.URL.String() != repo.URL.String() || got.SubPath != repo.SubPath || got.GoSource != repo.GoSource || got.Constraint.String() != "^2.1" || got.Default != repo.Default {
+		t.Logf("%+v\n", got)
+		t.Fatal("incorrect decoded repo")
+	}
+
+	// The promoted fields and methods of the embedded Version still work.
+	if repo.Major != 2 || repo.Precision() != 1 {
+		t.Fatal("incorrect promoted Version fields")
+	}
+}
+
+var defaultVersionTextTests = []DefaultVersion{
+	{},
+	DefaultLatest,
+	DefaultV0,
+	DefaultBranch("main"),
+	DefaultBranch("release 1"),
+}
+
+// Tests that default version policies round-trip through their text form.
+func TestDefaultVersionText(t *testing.T) {
+	for _, d := range defaultVersionTextTests {
+		text, err := d.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got DefaultVersion
+		if err := got.UnmarshalText(text); err != nil || got != d {
+			t.Fatalf("%q: got %v (err=%v) want %v\n", text, got, err, d)
+		}
+	}
+	for _, s := range []string{"", "master", "branch ", "Latest"} {
+		var d DefaultVersion
+		if err := d.UnmarshalText([]byte(s)); err == nil {
+			t.Fatalf("%q: want error\n", s)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// gitea is a Matcher that represents a single Gitea (or Forgejo) user or
+// organization.
+type gitea struct {
+	host, owner string
+}
+
+// giteaGoSource returns a go-source meta-tag for the given repository and go
+// get URL. Gitea source-view URLs differ for branches and tags, so the kind of
+// the chosen ref (see Repo.Ref) is used, or a branch if none was chosen yet.
+func giteaGoSource(r *Repo, u *url.URL) string {
+	gtURL := webURL(r)
+	ref := sourceRef(r)
+	kind := "branch"
+	if strings.HasPrefix(r.Ref, "refs/tags/") {
+		kind = "tag"
+	}
+
+	// Build a directory-view URL like so:
+	//
+	//  https://gitea.example.com/owner/pkg/src/tag/v2.1.0{/dir}
+	//
+	dirURL := gtURL
+	dirURL.Path = path.Join(dirURL.Path, "src", kind, ref)
+	dir := dirURL.String() + "{/dir}"
+
+	// Build a file-view URL like so:
+	//
+	//  https://gitea.example.com/owner/pkg/src/tag/v2.1.0{/dir}/{file}#L{line}
+	//
+	file := dirURL.String() + "{/dir}/{file}#L{line}"
+
+	return goSource(r, u, dir, file)
+}
+
+// Match implements the Matcher interface.
+func (g gitea) Match(u *url.URL) (repo *Repo, err error) {
+	repo, err = matchDashed(u, g.host, g.owner, giteaGoSource)
+	if err != nil {
+		return nil, err
+	}
+	repo.URL.Path += ".git"
+	return
+}
+
+// Gitea returns a URL Matcher that operates on a single user or organization
+// of a Gitea or Forgejo instance at the given host. Import paths are mapped to
+// repositories as with GitHub, for instance if the service was running at
+// example.com, the host was git.example.com and the owner string was "bob", it
+// would match URLS in the pattern of:
+//
+//  example.com/pkg.v3 → git.example.com/bob/pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/folder/pkg.v3 → git.example.com/bob/folder-pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/pkg.v3/folder/subpkg → git.example.com/bob/pkg (branch/tag v3, v3.N, or v3.N.M)
+//
+// The go-source meta tag links to the branch or tag that was chosen.
+func Gitea(host, owner string) Matcher {
+	return gitea{host, owner}
+}
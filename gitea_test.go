@@ -0,0 +1,63 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/url"
+	"testing"
+)
+
+var giteaGoSourceTests = []struct {
+	ref, goSource string
+}{
+	{
+		"",
+		"example.com/folder/pkg.v2 _ " +
+			"https://git.example.com/bob/folder-pkg/src/branch/v2{/dir} " +
+			"https://git.example.com/bob/folder-pkg/src/branch/v2{/dir}/{file}#L{line}",
+	},
+	{
+		"refs/tags/v2.1.0",
+		"example.com/folder/pkg.v2 _ " +
+			"https://git.example.com/bob/folder-pkg/src/tag/v2.1.0{/dir} " +
+			"https://git.example.com/bob/folder-pkg/src/tag/v2.1.0{/dir}/{file}#L{line}",
+	},
+	{
+		"refs/heads/v2",
+		"example.com/folder/pkg.v2 _ " +
+			"https://git.example.com/bob/folder-pkg/src/branch/v2{/dir} " +
+			"https://git.example.com/bob/folder-pkg/src/branch/v2{/dir}/{file}#L{line}",
+	},
+}
+
+// Tests the Gitea URL matcher.
+func TestGitea(t *testing.T) {
+	u, err := url.Parse("https://example.com/folder/pkg.v2/subpkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tst := range giteaGoSourceTests {
+		repo, err := Gitea("git.example.com", "bob").Match(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if repo.URL.Host != "git.example.com" || repo.URL.Path != "bob/folder-pkg.git" || repo.SubPath != "subpkg" {
+			t.Log(repo.URL, repo.SubPath)
+			t.Fatal("incorrect repo")
+		}
+
+		// Choose the ref, as the Handler does.
+		if len(tst.ref) > 0 {
+			repo.Ref = tst.ref
+			repo.GoSource = repo.GoSourceFunc(repo)
+		}
+		if repo.GoSource != tst.goSource {
+			t.Log(tst.ref)
+			t.Log("want", tst.goSource)
+			t.Log("got", repo.GoSource)
+			t.Fatal("incorrect go-source")
+		}
+	}
+}
@@ -15,14 +15,36 @@ import (
 
 var rePkgVersion = regexp.MustCompile(`^([a-zA-Z0-9-]+).(v[0-9]+[\.]?[0-9]*[\.]?[0-9]*(?:\-unstable)?)`)
 
+// reMajorSuffix matches the major version suffix element of Go module paths,
+// e.g. the "v2" of "example.com/pkg/v2".
+var reMajorSuffix = regexp.MustCompile(`^v([2-9]|[1-9][0-9]+)$`)
+
+// unversionedModule is the constraint of Go module paths without a major
+// version suffix, which are v0 or v1.
+var unversionedModule = MustParseConstraint("<2")
+
 // github is a Matcher that represents a single GitHub user or organization.
 type github struct {
 	host, user string
+
+	// Whether the path elements before the versioned element name a
+	// repository holding multiple modules, see GitHubMonorepo.
+	monorepo bool
+
+	// If non-nil, the path element before the versioned element names the
+	// user (which must be in the map), see GitHubUsers.
+	users map[string]bool
+
+	// Whether import paths are Go module paths, see GitHubModules.
+	modules bool
+
+	// The policy for import paths without a version, see GitHubUnversioned.
+	unversioned DefaultVersion
 }
 
-// githubGoSource returns a go-source meta-tag for the given repository and go
-// get URL.
-func githubGoSource(r *Repo, u *url.URL) string {
+// goSource returns a go-source meta-tag for the given repository and go get
+// URL, given the directory-view and file-view URL templates.
+func goSource(r *Repo, u *url.URL, dir, file string) string {
 	// The Go package path corresponding to the repository root, for example:
 	//
 	//  right: azul3d.org/gfx.v2
@@ -33,18 +55,88 @@ func githubGoSource(r *Repo, u *url.URL) string {
 	// Default to godoc.org's home:
 	home := "_"
 
-	// A basic GitHub repository URL.
-	ghURL := *r.URL
-	if len(ghURL.Scheme) == 0 {
-		ghURL.Scheme = "https"
+	return strings.Join([]string{prefix, home, dir, file}, " ")
+}
+
+// refName returns the short name of the branch or tag of the repository's
+// version, prefixed by the subdirectory for a module in a subdirectory (e.g.
+// "lint/v2"). If the ref has been chosen already (see Repo.Ref), its short
+// name is returned instead.
+func refName(r *Repo) string {
+	if len(r.Ref) > 0 {
+		return strings.TrimPrefix(strings.TrimPrefix(r.Ref, "refs/heads/"), "refs/tags/")
+	}
+	return path.Join(r.Subdir, r.Version.String())
+}
+
+// sourceRef returns the ref name of the repository's version followed by the
+// directory of the package in the repository, as used in the URLs of source
+// code viewers. For a module in a subdirectory both are prefixed by the
+// subdirectory (e.g. "lint/v2/lint"), otherwise it is just the ref name.
+func sourceRef(r *Repo) string {
+	return path.Join(refName(r), r.Subdir)
+}
+
+// webURL returns a copy of the repository URL for building web URLs, with the
+// scheme defaulting to https.
+func webURL(r *Repo) url.URL {
+	w := *r.URL
+	if len(w.Scheme) == 0 {
+		w.Scheme = "https"
+	}
+	return w
+}
+
+// trimGitSuffix returns a copy of the repository with the .git suffix removed
+// from its URL.
+func trimGitSuffix(r *Repo) *Repo {
+	cpy := *r
+	web := *r.URL
+	web.Path = strings.TrimSuffix(web.Path, ".git")
+	cpy.URL = &web
+	return &cpy
+}
+
+// matchDashed matches the import path of the given URL to a repository at the
+// given host and owner path, named as with GitHub: everything in the path up
+// to the versioned element is part of the repository name, with slashes
+// replaced by dashes. The go-source meta-tag is built by the given function,
+// and rebuilt by the Handler once the ref is chosen (with any .git suffix of
+// the repository URL removed).
+func matchDashed(u *url.URL, host, owner string, gs func(r *Repo, u *url.URL) string) (*Repo, error) {
+	p, err := splitPkgPath(u)
+	if err != nil {
+		return nil, err
+	}
+	repo := &Repo{
+		Version: p.version,
+		SubPath: p.subPath,
+		URL: &url.URL{
+			Scheme: u.Scheme,
+			Host:   host,
+			Path:   path.Join(owner, strings.Join(append(p.dir, p.name), "-")),
+		},
+	}
+	repo.GoSource = gs(repo, u)
+	repo.GoSourceFunc = func(r *Repo) string {
+		return gs(trimGitSuffix(r), u)
 	}
+	return repo, nil
+}
+
+// githubGoSource returns a go-source meta-tag for the given repository and go
+// get URL.
+func githubGoSource(r *Repo, u *url.URL) string {
+	// A basic GitHub repository URL.
+	ghURL := webURL(r)
+	ref := sourceRef(r)
 
 	// Build a directory-view URL like so:
 	//
 	//  https://github.com/go-yaml/yaml/tree/v2{/dir}
 	//
 	dirURL := ghURL
-	dirURL.Path = path.Join(dirURL.Path, "tree", r.Version.String())
+	dirURL.Path = path.Join(dirURL.Path, "tree", ref)
 	dir := dirURL.String() + "{/dir}"
 
 	// Build a file-view URL like so:
@@ -52,14 +144,26 @@ func githubGoSource(r *Repo, u *url.URL) string {
 	//  https://github.com/go-yaml/yaml/blob/v2{/dir}/{file}#L{line}
 	//
 	fileURL := ghURL
-	fileURL.Path = path.Join(fileURL.Path, "blob", r.Version.String())
+	fileURL.Path = path.Join(fileURL.Path, "blob", ref)
 	file := fileURL.String() + "{/dir}/{file}#L{line}"
 
-	return strings.Join([]string{prefix, home, dir, file}, " ")
+	return goSource(r, u, dir, file)
 }
 
-// Match implements the Matcher interface.
-func (user github) Match(u *url.URL) (repo *Repo, err error) {
+// pkgPath is a package import path split around its versioned path element,
+// e.g. "folder/pkg.v3/subpkg".
+type pkgPath struct {
+	dir     []string // Path elements before the versioned one, e.g. ["folder"].
+	name    string   // Package name of the versioned element, e.g. "pkg".
+	version Version  // Version of the versioned element, e.g. v3.
+	subPath string   // Path after the versioned element, e.g. "subpkg".
+}
+
+// splitPkgPath splits the path of the given URL around its single versioned
+// path element (like "pkg.v3"). If the path has no or multiple versioned
+// elements, or empty elements, ErrNotPackageURL is returned. If the version is
+// more precise than a major version, a HTTPError is returned.
+func splitPkgPath(u *url.URL) (*pkgPath, error) {
 	// Split the path elements. If any element is an empty string then it
 	// is because there are two consecutive slashes ("/a//b/c") or the path
 	// ends with a trailing slash ("example.com/pkg.v1/").
@@ -97,31 +201,161 @@ func (user github) Match(u *url.URL) (repo *Repo, err error) {
 
 	// Parse the version string.
 	v := ParseVersion(version)
-	if v.Minor > 0 || v.Patch > 0 {
+	if v.Precision() > 1 {
 		return nil, &HTTPError{
 			error:  fmt.Errorf("Import path may only contain major version."),
 			Status: http.StatusNotFound,
 		}
 	}
+	return &pkgPath{
+		dir:     s[:versionElem],
+		name:    pkgName,
+		version: v,
+		subPath: strings.Join(s[versionElem+1:], "/"),
+	}, nil
+}
+
+// matchModule implements the Match method for Go module paths, see
+// GitHubModules.
+func (user github) matchModule(u *url.URL) (*Repo, error) {
+	s := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	for _, elem := range s {
+		if len(elem) == 0 || rePkgVersion.MatchString(elem) {
+			// Path has empty elements, or is not a module path.
+			return nil, ErrNotPackageURL
+		}
+	}
+	newRepo := func(v Version, c *Constraint, subPath []string) *Repo {
+		repo := &Repo{
+			Version:    v,
+			Constraint: c,
+			SubPath:    strings.Join(subPath, "/"),
+			URL: &url.URL{
+				Scheme: u.Scheme,
+				Host:   user.host,
+				Path:   path.Join(user.user, s[0]),
+			},
+		}
+
+		// Attach the go-source meta-tag, it is rebuilt by the Handler to
+		// link the chosen tag.
+		repo.GoSource = githubGoSource(repo, u)
+		repo.GoSourceFunc = func(r *Repo) string {
+			return githubGoSource(trimGitSuffix(r), u)
+		}
+		repo.URL.Path += ".git"
+		return repo
+	}
+
+	// Without a major version suffix, the path is v1 or v0; falling back to
+	// master if there are no such tags.
+	repo := newRepo(ParseVersion("v1"), unversionedModule, s[1:])
+	repo.Fallback = newRepo(ParseVersion("v0"), nil, s[1:])
+	if len(s) < 2 || !reMajorSuffix.MatchString(s[1]) {
+		return repo, nil
+	}
+
+	// The path may be either the major version, or a subpackage directory
+	// named like it: the latter is used if the major version does not exist.
+	major := newRepo(ParseVersion(s[1]), nil, s[2:])
+	major.Fallback = repo
+	return major, nil
+}
+
+// matchUnversioned implements the Match method for import paths without a
+// version, see GitHubUnversioned.
+func (user github) matchUnversioned(u *url.URL) (*Repo, error) {
+	s := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	for _, elem := range s {
+		if len(elem) == 0 || rePkgVersion.MatchString(elem) {
+			// Path has empty elements, or is versioned.
+			return nil, ErrNotPackageURL
+		}
+	}
+	repo := &Repo{
+		Version: ParseVersion("v0"),
+		Default: user.unversioned,
+		SubPath: strings.Join(s[1:], "/"),
+		URL: &url.URL{
+			Scheme: u.Scheme,
+			Host:   user.host,
+			Path:   path.Join(user.user, s[0]),
+		},
+	}
+
+	// Attach the go-source meta-tag, it is rebuilt by the Handler to link
+	// the chosen ref.
+	repo.GoSource = githubGoSource(repo, u)
+	repo.GoSourceFunc = func(r *Repo) string {
+		return githubGoSource(trimGitSuffix(r), u)
+	}
+	repo.URL.Path += ".git"
+	return repo, nil
+}
+
+// Match implements the Matcher interface.
+func (user github) Match(u *url.URL) (repo *Repo, err error) {
+	if user.modules {
+		return user.matchModule(u)
+	}
+	p, err := splitPkgPath(u)
+	if err == ErrNotPackageURL && user.unversioned != (DefaultVersion{}) {
+		return user.matchUnversioned(u)
+	}
+	if err != nil {
+		return nil, err
+	}
 
 	// Everything in the path up to the path element index [found] is part
 	// of the repository name. We replace all slashes with dashes (the same
 	// thing GitHub does if you try to create a repository with slashes in
 	// the name).
-	repoName := strings.Join(append(s[:versionElem], pkgName), "-")
-	repoSubPath := strings.Join(s[versionElem+1:], "/")
+	//
+	// In monorepo mode the first path element is the repository name instead,
+	// and the rest name the subdirectory holding the module.
+	//
+	// In user path mode the path element before the versioned one is the
+	// user instead, or the default user if there is none.
+	var (
+		owner    = user.user
+		repoName = strings.Join(append(p.dir, p.name), "-")
+		subdir   string
+	)
+	switch {
+	case user.users != nil:
+		switch len(p.dir) {
+		case 0:
+		case 1:
+			owner = p.dir[0]
+		default:
+			return nil, ErrNotPackageURL
+		}
+		if len(owner) == 0 || !user.users[strings.ToLower(owner)] {
+			// Not a permitted user.
+			return nil, ErrNotPackageURL
+		}
+		repoName = p.name
+	case user.monorepo && len(p.dir) > 0:
+		repoName = p.dir[0]
+		subdir = strings.Join(append(p.dir[1:], p.name), "/")
+	}
 	repo = &Repo{
-		Version: v,
-		SubPath: repoSubPath,
+		Version: p.version,
+		SubPath: p.subPath,
+		Subdir:  subdir,
 		URL: &url.URL{
 			Scheme: u.Scheme,
 			Host:   user.host,
-			Path:   path.Join(user.user, repoName),
+			Path:   path.Join(owner, repoName),
 		},
 	}
 
-	// Attach the go-source meta-tag.
+	// Attach the go-source meta-tag. It is rebuilt by the Handler once the ref
+	// is chosen, at which point the repository URL has the .git suffix.
 	repo.GoSource = githubGoSource(repo, u)
+	repo.GoSourceFunc = func(r *Repo) string {
+		return githubGoSource(trimGitSuffix(r), u)
+	}
 
 	// TODO(slimsag): godoc.org requires that repos end in .git: very strange.
 	repo.URL.Path += ".git"
@@ -140,7 +374,7 @@ func (user github) Match(u *url.URL) (repo *Repo, err error) {
 //  example.com/pkg.v3-unstable → github.com/bob/pkg (branch/tag v3-unstable, v3.N-unstable, or v3.N.M-unstable)
 //
 func GitHub(user string) Matcher {
-	return github{"github.com", user}
+	return github{host: "github.com", user: user}
 }
 
 // GitHub returns a URL Matcher that operates on a single GitHub user or organization
@@ -152,5 +386,88 @@ func GitHub(user string) Matcher {
 //  example.com/folder/pkg.v3 → gitlab.com/bob/folder-pkg (branch/tag v3, v3.N, or v3.N.M)
 //
 func GitHubCustomHost(host, user string) Matcher {
-	return github{host, user}
+	return github{host: host, user: user}
+}
+
+// GitHubMonorepo is like GitHub, except that the first path element names a
+// repository holding multiple modules, each living in a subdirectory and
+// tagged with the subdirectory as prefix (e.g. "lint/v3.1.0"). For instance if
+// the service was running at example.com and the user string was "bob", it
+// would match URLS in the pattern of:
+//
+//  example.com/tools/lint.v3 → github.com/bob/tools, subdirectory lint (tag lint/v3, lint/v3.N, or lint/v3.N.M)
+//  example.com/tools/cmd/lint.v3 → github.com/bob/tools, subdirectory cmd/lint (tag cmd/lint/v3, ...)
+//  example.com/tools/lint.v3/subpkg → github.com/bob/tools, subdirectory lint (tag lint/v3, ...)
+//  example.com/pkg.v3 → github.com/bob/pkg (branch/tag v3, v3.N, or v3.N.M)
+//
+// The subdirectory is served as the fourth field of the go-import meta tag
+// (see Repo.Subdir), which requires Go 1.25 or later; modules at the root of a
+// repository are served as usual.
+//
+func GitHubMonorepo(user string) Matcher {
+	return github{host: "github.com", user: user, monorepo: true}
+}
+
+// GitHubModules returns a URL Matcher that operates on a single GitHub user or
+// organization, using Go module paths: the first path element names the
+// repository, and a following "/vN" element (N >= 2) the major version. For
+// instance if the service was running at example.com and the user string was
+// "bob", it would match URLS in the pattern of:
+//
+//  example.com/pkg → github.com/bob/pkg (greatest v1.N.M or v0.N.M tag, or master)
+//  example.com/pkg/subpkg → github.com/bob/pkg (greatest v1.N.M or v0.N.M tag, or master)
+//  example.com/pkg/v2 → github.com/bob/pkg (branch/tag v2, v2.N, or v2.N.M)
+//  example.com/pkg/v2/subpkg → github.com/bob/pkg (branch/tag v2, v2.N, or v2.N.M)
+//
+// If the repository has no v2 branch or tag, example.com/pkg/v2 is treated as
+// the "v2" subpackage of example.com/pkg instead. Only the element following
+// the repository name can be a major version, e.g. example.com/pkg/sub/v2 is
+// always a subpackage. Paths with elements like "pkg.v2" are not matched.
+func GitHubModules(user string) Matcher {
+	return github{host: "github.com", user: user, modules: true}
+}
+
+// GitHubUnversioned is like GitHub, except that import paths without a version
+// are matched as well, choosing the branch or tag of the repository using the
+// given policy. The first path element of such import paths names the
+// repository. For instance if the service was running at example.com, the user
+// string was "bob" and the policy was DefaultLatest, it would match URLS in the
+// pattern of:
+//
+//  example.com/pkg.v3 → github.com/bob/pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/pkg → github.com/bob/pkg (greatest release of the greatest major version)
+//  example.com/pkg/subpkg → github.com/bob/pkg (greatest release of the greatest major version)
+//
+// The Handler reports the chosen major version in the X-Semver-Major response
+// header. As import paths without a version cannot be told apart from other
+// pages of the site (e.g. "example.com/about"), the Handler only handles them
+// for go get and Git requests, and leaves them unhandled if the repository
+// does not exist.
+func GitHubUnversioned(user string, d DefaultVersion) Matcher {
+	return github{host: "github.com", user: user, unversioned: d}
+}
+
+// GitHubUsers returns a URL Matcher that operates on multiple GitHub users or
+// organizations, in the style of gopkg.in: the path element before the
+// versioned one names the user. Only the default user and the allowed users
+// (compared case-insensitively) are matched, such that the service does not
+// serve arbitrary GitHub accounts. For instance if the service was running at
+// example.com, the default user string was "bob" and "alice" was allowed, it
+// would match URLS in the pattern of:
+//
+//  example.com/pkg.v3 → github.com/bob/pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/alice/pkg.v3 → github.com/alice/pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/alice/pkg.v3/subpkg → github.com/alice/pkg (branch/tag v3, v3.N, or v3.N.M)
+//
+// But not example.com/eve/pkg.v3 or example.com/alice/folder/pkg.v3. If the
+// default user string is empty, URLs without a user are not matched.
+func GitHubUsers(defaultUser string, allowed ...string) Matcher {
+	users := make(map[string]bool, len(allowed)+1)
+	if len(defaultUser) > 0 {
+		users[strings.ToLower(defaultUser)] = true
+	}
+	for _, u := range allowed {
+		users[strings.ToLower(u)] = true
+	}
+	return github{host: "github.com", user: defaultUser, users: users}
 }
@@ -5,7 +5,10 @@
 package semver
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -84,6 +87,8 @@ var gitHubTests = []struct {
 	{"a/b/", "", "", false},
 	{"a/b.v3/", "", "", false},
 	{"a.v3/b/c.v3", "", "", false},
+	{"pkg.v3.0", "", "", false},
+	{"pkg.v3.1", "", "", false},
 }
 
 // Tests the GitHub URL matcher.
@@ -128,3 +133,278 @@ func TestGitHub(t *testing.T) {
 		}
 	}
 }
+
+var gitHubMonorepoTests = []struct {
+	url, github, subdir, subpath string
+}{
+	{"pkg.v3", "bob/pkg.git", "", ""},
+	{"pkg.v3/subpkg", "bob/pkg.git", "", "subpkg"},
+	{"tools/lint.v1", "bob/tools.git", "lint", ""},
+	{"tools/cmd/lint.v1", "bob/tools.git", "cmd/lint", ""},
+	{"tools/lint.v1/subpkg", "bob/tools.git", "lint", "subpkg"},
+}
+
+// Tests the GitHub monorepo URL matcher.
+func TestGitHubMonorepo(t *testing.T) {
+	matcher := GitHubMonorepo("bob")
+	for _, tst := range gitHubMonorepoTests {
+		u, err := url.Parse(tst.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo, err := matcher.Match(u)
+		if err != nil {
+			t.Log(u)
+			t.Fatal("Test is valid but matcher returned:", err)
+		}
+		if repo.URL.Path != tst.github || repo.Subdir != tst.subdir || repo.SubPath != tst.subpath {
+			t.Log(u)
+			t.Logf("want %q %q %q\n", tst.github, tst.subdir, tst.subpath)
+			t.Logf("got %q %q %q\n", repo.URL.Path, repo.Subdir, repo.SubPath)
+			t.Fatal("incorrect repo")
+		}
+	}
+}
+
+// Tests that a Handler using the GitHub monorepo URL matcher only serves the
+// subdirectory field of the go-import meta tag for modules in a subdirectory.
+func TestGitHubMonorepoHandle(t *testing.T) {
+	h := &Handler{
+		Host:    "example.com",
+		Matcher: GitHubMonorepo("bob"),
+		Client: &http.Client{Transport: fileTransport{
+			"https://github.com/bob/tools.git/info/refs?service=git-upload-pack": "testdata/github-bob-tools",
+		}},
+	}
+	for _, tst := range []struct {
+		path, goImport string
+	}{
+		{"/tools.v1", `<meta name="go-import" content="example.com/tools.v1 git https://example.com/tools.v1">`},
+		{"/tools.v1/lint", `<meta name="go-import" content="example.com/tools.v1 git https://example.com/tools.v1">`},
+		{"/tools/lint.v1", `<meta name="go-import" content="example.com/tools/lint.v1 git https://example.com/tools/lint.v1 lint">`},
+		{"/tools/lint.v1/subpkg", `<meta name="go-import" content="example.com/tools/lint.v1 git https://example.com/tools/lint.v1 lint">`},
+	} {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "https://example.com"+tst.path+"?go-get=1", nil)
+		if s, err := h.Handle(w, r); s != Handled || err != nil {
+			t.Fatalf("got status %v err %v\n", s, err)
+		}
+		if !strings.Contains(w.Body.String(), tst.goImport) {
+			t.Log(tst.path)
+			t.Log(w.Body.String())
+			t.Fatal("missing", tst.goImport)
+		}
+	}
+}
+
+var gitHubUsersTests = []struct {
+	url, github, subpath string
+	valid                bool
+}{
+	{"pkg.v3", "bob/pkg.git", "", true},
+	{"alice/pkg.v3", "alice/pkg.git", "", true},
+	{"Alice/pkg.v3/folder/subpkg", "Alice/pkg.git", "folder/subpkg", true},
+	{"bob/pkg.v1", "bob/pkg.git", "", true},
+	{"eve/pkg.v3", "", "", false},
+	{"alice/folder/pkg.v3", "", "", false},
+	{"alice/pkg", "", "", false},
+}
+
+// Tests the gopkg.in-style GitHub URL matcher.
+func TestGitHubUsers(t *testing.T) {
+	matcher := GitHubUsers("bob", "alice")
+	for _, tst := range gitHubUsersTests {
+		u, err := url.Parse(tst.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo, err := matcher.Match(u)
+		if !tst.valid {
+			if err != ErrNotPackageURL {
+				t.Log(u)
+				t.Fatal("want ErrNotPackageURL, got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Log(u)
+			t.Fatal("Test is valid but matcher returned:", err)
+		}
+		if repo.URL.Path != tst.github || repo.SubPath != tst.subpath {
+			t.Log(u)
+			t.Logf("want %q %q\n", tst.github, tst.subpath)
+			t.Logf("got %q %q\n", repo.URL.Path, repo.SubPath)
+			t.Fatal("incorrect repo")
+		}
+	}
+
+	// Without a default user, the short form is not matched.
+	u, _ := url.Parse("pkg.v3")
+	if _, err := GitHubUsers("", "alice").Match(u); err != ErrNotPackageURL {
+		t.Fatal("want ErrNotPackageURL, got", err)
+	}
+}
+
+var gitHubModulesTests = []struct {
+	url, github, version, subpath, fallback string
+	valid                                   bool
+}{
+	{"pkg", "bob/pkg.git", "v1", "", "v0", true},
+	{"pkg/subpkg", "bob/pkg.git", "v1", "subpkg", "v0", true},
+	{"pkg/v2", "bob/pkg.git", "v2", "", "v1", true},
+	{"pkg/v2/subpkg", "bob/pkg.git", "v2", "subpkg", "v1", true},
+	{"pkg/v10", "bob/pkg.git", "v10", "", "v1", true},
+	{"pkg/v1", "bob/pkg.git", "v1", "v1", "v0", true},
+	{"pkg/v02", "bob/pkg.git", "v1", "v02", "v0", true},
+	{"pkg/sub/v2", "bob/pkg.git", "v1", "sub/v2", "v0", true},
+	{"pkg/", "", "", "", "", false},
+	{"pkg.v2", "", "", "", "", false},
+	{"pkg/sub.v2", "", "", "", "", false},
+}
+
+// Tests the Go module path GitHub URL matcher.
+func TestGitHubModules(t *testing.T) {
+	matcher := GitHubModules("bob")
+	for _, tst := range gitHubModulesTests {
+		u, err := url.Parse(tst.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo, err := matcher.Match(u)
+		if !tst.valid {
+			if err != ErrNotPackageURL {
+				t.Log(u)
+				t.Fatal("want ErrNotPackageURL, got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Log(u)
+			t.Fatal("Test is valid but matcher returned:", err)
+		}
+		if repo.URL.Path != tst.github || repo.Version.String() != tst.version || repo.SubPath != tst.subpath {
+			t.Log(u)
+			t.Logf("want %q %q %q\n", tst.github, tst.version, tst.subpath)
+			t.Logf("got %q %q %q\n", repo.URL.Path, repo.Version, repo.SubPath)
+			t.Fatal("incorrect repo")
+		}
+		if repo.Fallback == nil || repo.Fallback.Version.String() != tst.fallback {
+			t.Log(u)
+			t.Fatal("incorrect fallback", repo.Fallback)
+		}
+	}
+}
+
+// Tests a Handler using the Go module path GitHub URL matcher.
+func TestGitHubModulesHandle(t *testing.T) {
+	h := &Handler{
+		Host:    "example.com",
+		Matcher: GitHubModules("bob"),
+		Client: &http.Client{Transport: fileTransport{
+			"https://github.com/bob/pkg.git/info/refs?service=git-upload-pack": "testdata/github-bob-modules",
+		}},
+	}
+	for _, tst := range []struct {
+		path, prefix, ref string
+	}{
+		{"/pkg", "example.com/pkg", "v1.1.0"},
+		{"/pkg/sub", "example.com/pkg", "v1.1.0"},
+		{"/pkg/v2", "example.com/pkg/v2", "v2.0.0"},
+		{"/pkg/v2/sub", "example.com/pkg/v2", "v2.0.0"},
+		{"/pkg/v3", "example.com/pkg", "v1.1.0"},
+	} {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "https://example.com"+tst.path+"?go-get=1", nil)
+		if s, err := h.Handle(w, r); s != Handled || err != nil {
+			t.Fatalf("got status %v err %v\n", s, err)
+		}
+		for _, want := range []string{
+			`<meta name="go-import" content="` + tst.prefix + ` git https://` + tst.prefix + `">`,
+			`<meta name="go-source" content="` + tst.prefix + ` _ https://github.com/bob/pkg/tree/` + tst.ref + `{/dir}`,
+		} {
+			if !strings.Contains(w.Body.String(), want) {
+				t.Log(tst.path)
+				t.Log(w.Body.String())
+				t.Fatal("missing", want)
+			}
+		}
+	}
+}
+
+// Tests a Handler using the GitHub URL matcher for unversioned import paths.
+func TestGitHubUnversionedHandle(t *testing.T) {
+	client := &http.Client{Transport: fileTransport{
+		"https://github.com/bob/pkg.git/info/refs?service=git-upload-pack": "testdata/github-bob-modules",
+	}}
+	for _, tst := range []struct {
+		d                 DefaultVersion
+		path, hash, major string
+	}{
+		{DefaultLatest, "/pkg", "5555555555555555555555555555555555555555", "2"},
+		{DefaultLatest, "/pkg/subpkg", "5555555555555555555555555555555555555555", "2"},
+		{DefaultLatest, "/pkg.v1", "3333333333333333333333333333333333333333", ""},
+		{DefaultV0, "/pkg", "1111111111111111111111111111111111111111", "0"},
+		{DefaultBranch("master"), "/pkg", "1111111111111111111111111111111111111111", ""},
+	} {
+		h := &Handler{
+			Host:    "example.com",
+			Matcher: GitHubUnversioned("bob", tst.d),
+			Client:  client,
+		}
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "https://example.com"+tst.path+"/info/refs?service=git-upload-pack", nil)
+		if s, err := h.Handle(w, r); s != Handled || err != nil || w.Code != http.StatusOK {
+			t.Fatalf("got status %v err %v code %d\n", s, err, w.Code)
+		}
+		refs, err := gitParseRefs(w.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if refs.mainID != tst.hash || w.Header().Get("X-Semver-Major") != tst.major {
+			t.Logf("%v %s\n", tst.d, tst.path)
+			t.Fatalf("got %q major %q, want %q major %q\n", refs.mainID, w.Header().Get("X-Semver-Major"), tst.hash, tst.major)
+		}
+	}
+
+	// Without a policy, unversioned paths are not matched.
+	u, _ := url.Parse("pkg")
+	if _, err := GitHub("bob").Match(u); err != ErrNotPackageURL {
+		t.Fatal("want ErrNotPackageURL, got", err)
+	}
+}
+
+// Tests that a Handler using the GitHub URL matcher for unversioned import
+// paths leaves other pages of the site unhandled.
+func TestGitHubUnversionedUnhandled(t *testing.T) {
+	h := &Handler{
+		Host:    "example.com",
+		Matcher: GitHubUnversioned("bob", DefaultLatest),
+		Client: &http.Client{Transport: fileTransport{
+			"https://github.com/bob/pkg.git/info/refs?service=git-upload-pack": "testdata/github-bob-modules",
+		}},
+	}
+	for _, tst := range []struct {
+		url    string
+		status Status
+	}{
+		{"https://example.com/about", Unhandled},
+		{"https://example.com/favicon.ico", Unhandled},
+		{"https://example.com/about?go-get=1", Unhandled},
+		{"https://example.com/about/info/refs?service=git-upload-pack", Unhandled},
+		{"https://example.com/pkg", Unhandled},
+		{"https://example.com/pkg?go-get=1", Handled},
+		{"https://example.com/pkg.v1", PkgPage},
+	} {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", tst.url, nil)
+		s, err := h.Handle(w, r)
+		if s != tst.status || err != nil {
+			t.Log(tst.url)
+			t.Fatalf("got status %v err %v, want status %v\n", s, err, tst.status)
+		}
+		if s == Handled && w.Code != http.StatusOK {
+			t.Log(tst.url)
+			t.Fatalf("got code %d\n", w.Code)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// gitlab is a Matcher that represents a single GitLab group or user.
+type gitlab struct {
+	host, group string
+}
+
+// gitlabGoSource returns a go-source meta-tag for the given repository and go
+// get URL.
+func gitlabGoSource(r *Repo, u *url.URL) string {
+	glURL := webURL(r)
+	ref := sourceRef(r)
+
+	// Build a directory-view URL like so:
+	//
+	//  https://gitlab.com/group/pkg/-/tree/v2{/dir}
+	//
+	dirURL := glURL
+	dirURL.Path = path.Join(dirURL.Path, "-", "tree", ref)
+	dir := dirURL.String() + "{/dir}"
+
+	// Build a file-view URL like so:
+	//
+	//  https://gitlab.com/group/pkg/-/blob/v2{/dir}/{file}#L{line}
+	//
+	fileURL := glURL
+	fileURL.Path = path.Join(fileURL.Path, "-", "blob", ref)
+	file := fileURL.String() + "{/dir}/{file}#L{line}"
+
+	return goSource(r, u, dir, file)
+}
+
+// Match implements the Matcher interface.
+func (g gitlab) Match(u *url.URL) (repo *Repo, err error) {
+	p, err := splitPkgPath(u)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unlike GitHub, GitLab supports nested groups: everything in the path up
+	// to the versioned element is the subgroup path of the repository.
+	repoPath := strings.Join(append(p.dir, p.name), "/")
+	repo = &Repo{
+		Version: p.version,
+		SubPath: p.subPath,
+		URL: &url.URL{
+			Scheme: u.Scheme,
+			Host:   g.host,
+			Path:   path.Join(g.group, repoPath),
+		},
+	}
+
+	// Attach the go-source meta-tag. It is rebuilt by the Handler once the ref
+	// is chosen, at which point the repository URL has the .git suffix.
+	repo.GoSource = gitlabGoSource(repo, u)
+	repo.GoSourceFunc = func(r *Repo) string {
+		return gitlabGoSource(trimGitSuffix(r), u)
+	}
+
+	repo.URL.Path += ".git"
+	return
+}
+
+// GitLab returns a URL Matcher that operates on a single group (or user) of a
+// GitLab instance at the given host (e.g. "gitlab.com"). Path elements before
+// the versioned one are treated as nested subgroups. For instance if the
+// service was running at example.com, the host was gitlab.com and the group
+// string was "bob", it would match URLS in the pattern of:
+//
+//  example.com/pkg.v3 → gitlab.com/bob/pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/team/pkg.v3 → gitlab.com/bob/team/pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/team/sub/pkg.v3 → gitlab.com/bob/team/sub/pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/team/pkg.v3/subpkg → gitlab.com/bob/team/pkg (branch/tag v3, v3.N, or v3.N.M)
+//
+func GitLab(host, group string) Matcher {
+	return gitlab{host, group}
+}
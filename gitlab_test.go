@@ -0,0 +1,79 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/url"
+	"testing"
+)
+
+var gitLabTests = []struct {
+	url, gitlab, subpath string
+	valid                bool
+}{
+	{"pkg.v3", "bob/pkg.git", "", true},
+	{"/pkg.v3", "bob/pkg.git", "", true},
+	{"team/pkg.v3", "bob/team/pkg.git", "", true},
+	{"team/sub/pkg.v2", "bob/team/sub/pkg.git", "", true},
+	{"team/sub/pkg.v2/folder/subpkg", "bob/team/sub/pkg.git", "folder/subpkg", true},
+	{"a/b", "", "", false},
+	{"a/b.v3/", "", "", false},
+	{"a.v3/b/c.v3", "", "", false},
+	{"pkg.v3.1", "", "", false},
+}
+
+// Tests the GitLab URL matcher.
+func TestGitLab(t *testing.T) {
+	matcher := GitLab("gitlab.example.com", "bob")
+	for _, tst := range gitLabTests {
+		u, err := url.Parse(tst.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo, err := matcher.Match(u)
+		if tst.valid && err != nil {
+			t.Log(u)
+			t.Fatal("Test is valid but matcher returned:", err)
+		} else if !tst.valid && err == nil {
+			t.Log(u)
+			t.Fatal("Test is invalid but matcher returned nil error!")
+		}
+		if !tst.valid {
+			continue
+		}
+		if repo.URL.Host != "gitlab.example.com" || repo.URL.Path != tst.gitlab {
+			t.Log(u)
+			t.Log("want", tst.gitlab)
+			t.Log("got", repo.URL.Host, repo.URL.Path)
+			t.Fatal("incorrect path")
+		}
+		if repo.SubPath != tst.subpath {
+			t.Log(u)
+			t.Log("want", tst.subpath)
+			t.Log("got", repo.SubPath)
+			t.Fatal("incorrect subpath")
+		}
+	}
+}
+
+// Tests the go-source meta tag of the GitLab URL matcher.
+func TestGitLabGoSource(t *testing.T) {
+	u, err := url.Parse("https://example.com/team/sub/pkg.v2/subpkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := GitLab("gitlab.com", "bob").Match(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "example.com/team/sub/pkg.v2 _ " +
+		"https://gitlab.com/bob/team/sub/pkg/-/tree/v2{/dir} " +
+		"https://gitlab.com/bob/team/sub/pkg/-/blob/v2{/dir}/{file}#L{line}"
+	if repo.GoSource != want {
+		t.Log("want", want)
+		t.Log("got", repo.GoSource)
+		t.Fatal("incorrect go-source")
+	}
+}
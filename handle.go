@@ -6,6 +6,8 @@ package semver
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -14,14 +16,16 @@ import (
 	"net/url"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // goGetTmpl is the HTML template that is served to the "go get" command line
 // tool.
 var goGetTmpl = template.Must(template.New("").Parse(`<html>
 	<head>
-		<meta name="go-import" content="{{.Prefix}} {{.VCS}} {{.RepoRoot}}">
+		<meta name="go-import" content="{{.Prefix}} {{.VCS}} {{.RepoRoot}}{{if .Subdir}} {{.Subdir}}{{end}}">
 		{{if .GoSource}}
 			<meta name="go-source" content="{{.GoSource}}">
 		{{end}}
@@ -47,13 +51,59 @@ type Handler struct {
 	// HTTP client to utilize for outgoing requests to Git servers, if nil then
 	// http.DefaultClient is used.
 	Client *http.Client
+
+	// UpstreamTimeout, if non-zero, is the maximum duration of matching the
+	// URL of a request and of each outgoing request to a Git server. Outgoing
+	// requests are also canceled once the incoming request is canceled (e.g.
+	// because the client has given up). If the timeout is exceeded, a 504
+	// Gateway Timeout response is sent.
+	UpstreamTimeout time.Duration
+
+	// Ordering is the ordering used to choose the greatest version among the
+	// branches and tags of a repository. The default, LegacyOrdering, treats
+	// unstable versions as less than any stable version, while SpecOrdering
+	// follows the semver precedence rules.
+	Ordering Ordering
+
+	// Scheme is the version scheme used to parse the names of the branches
+	// and tags of a repository, to choose the greatest version among them, and
+	// to match them to the major version requested in the import path. If nil
+	// then SemVer{Ordering: h.Ordering} is used (i.e. Ordering is ignored if a
+	// Scheme is set).
+	Scheme VersionScheme
+
+	// RefPatterns, if not empty, are the patterns used to map the names of
+	// the branches and tags of a repository to versions instead of parsing
+	// "refs/heads/<version>" and "refs/tags/<version>" names using the
+	// Scheme. For each ref the first matching pattern is used, and refs
+	// matching none of the patterns are ignored. For example:
+	//
+	//  RefPatterns: []semver.RefPattern{
+	//      semver.MustRefPattern(semver.RefTemplate("refs/heads/release-{major}.{minor}")),
+	//      semver.MustRefPattern(semver.RefTemplate("refs/tags/{major}.{minor}.{patch}")),
+	//      semver.MustRefPattern(semver.RefTemplate("refs/heads/v{major}.x")),
+	//  },
+	//
+	// The Scheme is still used to order the versions and match them to the
+	// major version requested in the import path.
+	RefPatterns []RefPattern
+}
+
+// scheme returns the version scheme to use, see the Scheme field.
+func (h *Handler) scheme() VersionScheme {
+	if h.Scheme != nil {
+		return h.Scheme
+	}
+	return SemVer{Ordering: h.Ordering}
 }
 
 // Handle asks this handler to handle the given HTTP request by writing the
 // appropriate response to the HTTP response writer.
 func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) (s Status, err error) {
 	// See if we can relate the requested URL to a repository URL.
-	repo, err := h.Match(h.sanitize(r.Method, r.URL))
+	ctx, cancel := h.upstreamContext(r.Context())
+	repo, err := matchContext(ctx, h.Matcher, h.sanitize(r.Method, r.URL))
+	cancel()
 	if err != nil {
 		if err == ErrNotPackageURL {
 			// For an invalid package path, the request was unhandled and there
@@ -71,6 +121,14 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) (s Status, err
 			return Handled, nil
 		}
 
+		// For a timeout, we send a HTTP error as well. Matchers making
+		// requests may return it wrapped (e.g. in a *url.Error).
+		if errors.Is(err, context.DeadlineExceeded) && r.Context().Err() == nil {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			fmt.Fprintf(w, "%s\n", err)
+			return Handled, nil
+		}
+
 		// For any other error, the request is unhandled and there was an
 		// error.
 		return Unhandled, err
@@ -88,6 +146,18 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) (s Status, err
 	// Parse the query.
 	query, _ := url.ParseQuery(r.URL.RawQuery)
 
+	// Import paths without a version are ambiguous with the other pages of
+	// the site (e.g. "/about"), so they are only handled for go get and Git
+	// requests.
+	unversioned := repo.Default != (DefaultVersion{})
+	if unversioned {
+		isGit := strings.HasSuffix(r.URL.Path, "/info/refs") || strings.HasSuffix(r.URL.Path, "/git-upload-pack")
+		isGoGet := r.Method == "GET" && len(query.Get("go-get")) > 0
+		if !isGit && !isGoGet {
+			return Unhandled, nil
+		}
+	}
+
 	// POST git-upload-pack is responded to by simply redirecting their actual
 	// request to the repository itself.
 	if r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/git-upload-pack") {
@@ -125,12 +195,25 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) (s Status, err
 
 	// Modify the binary /info/refs blob. We do this now so that go get will
 	// not find packages that do not exist.
-	refs, err, status := h.modifyRefs(target, repo.Version)
+	refs, err, status := h.modifyRefs(r.Context(), target, repo)
 	if err != nil {
+		if _, ok := err.(*upstreamError); ok && unversioned {
+			// The repository does not exist, so it was not an import path
+			// after all.
+			return Unhandled, nil
+		}
 		w.WriteHeader(status)
 		fmt.Fprintf(w, "%s\n", err)
 		return Handled, nil
 	}
+	if repo.GoSourceFunc != nil {
+		repo.GoSource = repo.GoSourceFunc(repo)
+	}
+
+	// Report the major version chosen for an import path without a version.
+	if repo.Default != (DefaultVersion{}) && repo.Version.Major >= 0 {
+		w.Header().Set("X-Semver-Major", strconv.Itoa(repo.Version.Major))
+	}
 
 	// If the client is the `go get` tool, then we serve them a small template
 	// that mostly just contains the go-import meta tag.
@@ -141,6 +224,7 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) (s Status, err
 			"VCS":      "git",
 			"RepoRoot": repoRoot,
 			"Prefix":   pkgRoot,
+			"Subdir":   repo.Subdir,
 			"PkgPath":  path.Join(h.Host, r.URL.Path),
 			"GoSource": repo.GoSource,
 		})
@@ -193,44 +277,64 @@ func (h *Handler) sanitize(method string, u *url.URL) *url.URL {
 }
 
 // modifyRefs downloads the given /info/refs URL and modifies it to download
-// the given version branch/tag of the git repository.
+// the branch/tag of the git repository chosen for the given repo's version or
+// constraint. The Ref field of the repo is set to the name of the chosen ref.
+// The download is canceled once the given context is done.
 //
 // The returned integer is the HTTP status code to be sent in the event of an
 // error.
-func (h *Handler) modifyRefs(target *url.URL, v Version) ([]byte, error, int) {
+func (h *Handler) modifyRefs(ctx context.Context, target *url.URL, repo *Repo) ([]byte, error, int) {
 	// Choose the appropriate HTTP client.
 	client := h.Client
 	if client == nil {
 		client = http.DefaultClient
 	}
+	ctx, cancel := h.upstreamContext(ctx)
+	defer cancel()
 
 	// Download the /info/refs?service=get-info-pack Git smart reply.
-	resp, err := client.Get(target.String())
+	req, err := http.NewRequest("GET", target.String(), nil)
 	if err != nil {
-		return nil, err, http.StatusBadGateway
+		return nil, err, http.StatusInternalServerError
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err, upstreamErrorStatus(ctx)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// E.g. the repository does not exist.
+		err := fmt.Errorf("Repository returned status %s.", resp.Status)
+		return nil, &upstreamError{err}, http.StatusBadGateway
+	}
 
 	// Read the entire body.
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err, http.StatusBadGateway
+		return nil, err, upstreamErrorStatus(ctx)
 	}
 
 	// Parse the info/refs data.
 	refs, err := gitParseRefs(data)
 	if err != nil {
-		return nil, err, http.StatusInternalServerError
+		return nil, &upstreamError{err}, http.StatusInternalServerError
 	}
 
 	// Swap refs/heads/master record hash with our desired tag/branch hash.
 	for _, ref := range refs.records {
 		if ref.Name == "refs/heads/master" {
-			hash, ok := h.chooseRef(refs.records, v)
+			chosen, ok := h.chooseRepoRef(refs.records, repo)
 			if !ok {
 				// We don't actually have the requested version.
 				return nil, fmt.Errorf("Requested version does not exist."), http.StatusNotFound
 			}
+			hash := chosen.BestHash()
+
+			// If HEAD points at master, it must point at the chosen commit
+			// as well, as that is what is checked out after cloning.
+			if refs.mainName == "HEAD" && refs.mainID == ref.Hash {
+				refs.mainID = hash
+			}
 			ref.Hash = hash
 			break
 		}
@@ -240,22 +344,272 @@ func (h *Handler) modifyRefs(target *url.URL, v Version) ([]byte, error, int) {
 	return refs.Bytes(), nil, http.StatusOK
 }
 
+// chooseRepoRef chooses the ref in the list for the given repo, using its
+// Constraint or otherwise its Version. If no ref can be chosen, the repo's
+// Fallback (if any) is tried instead, and the repo is replaced by it. The Ref
+// field of the repo is set to the name of the chosen ref.
+func (h *Handler) chooseRepoRef(refs []*gitRef, repo *Repo) (chosen *gitRef, ok bool) {
+	for {
+		// For a module in a subdirectory of the repository, only the tags
+		// prefixed with the subdirectory are candidates.
+		candidates := refs
+		if len(repo.Subdir) > 0 {
+			candidates = subdirRefs(refs, repo.Subdir)
+		}
+		if repo.Default != (DefaultVersion{}) {
+			var v Version
+			chosen, v, ok = h.chooseRefDefault(candidates, repo.Default)
+			if ok {
+				repo.Version = v
+			}
+		} else if repo.Constraint != nil {
+			chosen, ok = h.chooseRefConstraint(candidates, repo.Constraint)
+		} else {
+			chosen, ok = h.chooseRef(candidates, repo.Version)
+		}
+		if ok {
+			break
+		}
+		if repo.Fallback == nil {
+			return nil, false
+		}
+		*repo = *repo.Fallback
+	}
+
+	// Record the name of the chosen ref, as it is named in the repository.
+	repo.chosen = chosen
+	repo.Ref = chosen.Name
+	if len(repo.Subdir) > 0 && chosen.Name != "refs/heads/master" {
+		repo.Ref = "refs/tags/" + path.Join(repo.Subdir, strings.TrimPrefix(chosen.Name, "refs/tags/"))
+	}
+	return chosen, true
+}
+
+// upstreamError is an error returned by modifyRefs if the repository replied
+// with an error status or an invalid info/refs reply.
+type upstreamError struct {
+	error
+}
+
+// upstreamContext returns a context for upstream requests derived from the
+// given one, with the handler's UpstreamTimeout applied.
+func (h *Handler) upstreamContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if h.UpstreamTimeout > 0 {
+		return context.WithTimeout(ctx, h.UpstreamTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// upstreamErrorStatus returns the HTTP status code for a failed upstream
+// request using the given context.
+func upstreamErrorStatus(ctx context.Context) int {
+	if ctx.Err() == context.DeadlineExceeded {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// subdirRefs returns the tags in the list which are prefixed with the given
+// subdirectory (e.g. "refs/tags/lint/v1.2.3" for the subdirectory "lint"), as
+// copies with the prefix removed (e.g. "refs/tags/v1.2.3"). The master branch
+// ref is returned as well, such that v0 can still be served from it.
+func subdirRefs(refs []*gitRef, subdir string) []*gitRef {
+	var (
+		prefix   = "refs/tags/" + strings.Trim(subdir, "/") + "/"
+		filtered []*gitRef
+	)
+	for _, ref := range refs {
+		if ref.Name == "refs/heads/master" {
+			filtered = append(filtered, ref)
+			continue
+		}
+		if !strings.HasPrefix(ref.Name, prefix) {
+			continue
+		}
+		cpy := *ref
+		cpy.Name = "refs/tags/" + strings.TrimPrefix(ref.Name, prefix)
+		filtered = append(filtered, &cpy)
+	}
+	return filtered
+}
+
+// refVersion is a branch or tag ref with its version. The version is a named
+// field, such that its methods are not promoted onto refVersion.
 type refVersion struct {
-	Version
+	Version Version
 	*gitRef
 }
 type refsByVersion []refVersion
 
-func (s refsByVersion) Len() int           { return len(s) }
-func (s refsByVersion) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
-func (s refsByVersion) Less(i, j int) bool { return s[i].Version.Less(s[j].Version) }
+func (s refsByVersion) Len() int      { return len(s) }
+func (s refsByVersion) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// Less orders the refs by version using the default SemVer scheme, see
+// lessRefs.
+func (s refsByVersion) Less(i, j int) bool { return lessRefs(SemVer{}, s[i], s[j]) }
+
+// refsByScheme sorts refs by version using the ordering of a specific scheme.
+type refsByScheme struct {
+	refsByVersion
+	scheme VersionScheme
+}
+
+func (s refsByScheme) Less(i, j int) bool {
+	return lessRefs(s.scheme, s.refsByVersion[i], s.refsByVersion[j])
+}
+
+// lessRefs orders the refs by version using the given scheme. Refs with equal
+// versions (i.e. differing only in build metadata) are ordered such that the
+// preferred ref sorts last: a tag is less than a branch, and otherwise the ref
+// with the lexically lesser build metadata is the lesser one.
+func lessRefs(scheme VersionScheme, a, b refVersion) bool {
+	if c := scheme.Compare(a.Version, b.Version); c != 0 {
+		return c < 0
+	}
+	aHead := strings.HasPrefix(a.Name, "refs/heads/")
+	bHead := strings.HasPrefix(b.Name, "refs/heads/")
+	if aHead != bHead {
+		return bHead
+	}
+	return a.Version.Metadata < b.Version.Metadata
+}
 
 // chooseRef chooses the best ref in the list for the given version. It returns
 // ok=false if no ref could be chosen for the given version (i.e. the given
 // version does not exist).
-func (h *Handler) chooseRef(refs []*gitRef, v Version) (chosenHash string, ok bool) {
+//
+// The refs are parsed, matched to the major line of the given version, and
+// ordered using the handler's version scheme.
+//
+// Refs with pre-release versions (e.g. v2.1.0-rc.1) are only chosen if the
+// requested version is a pre-release itself, or if there is no release ref
+// with the requested major version at all.
+//
+// If several refs have the same version, a branch is always chosen over a tag.
+// Between refs that differ only in build metadata (e.g. v1.4.2+build.1 and
+// v1.4.2+build.2) the one with the lexically greatest metadata is chosen.
+func (h *Handler) chooseRef(refs []*gitRef, v Version) (chosen *gitRef, ok bool) {
 	var verList refsByVersion
-	var master *gitRef
+	scheme := h.scheme()
+	all, master := h.refVersions(refs)
+	for _, rv := range all {
+		// Ensure that the major lines (and unstable statuses) match the one
+		// we desire. If they don't then we skip this version.
+		if scheme.Line(rv.Version) != scheme.Line(v) || rv.Version.Unstable != v.Unstable {
+			continue
+		}
+
+		// Add it to the version list for sorting.
+		verList = append(verList, rv)
+	}
+
+	// Unless the requested version is itself a pre-release, pre-release refs
+	// (e.g. v2.1.0-rc.1) are only chosen when no release exists for the
+	// requested major version.
+	if len(v.PreRelease) == 0 {
+		var releases refsByVersion
+		for _, rv := range verList {
+			if len(rv.Version.PreRelease) == 0 {
+				releases = append(releases, rv)
+			}
+		}
+		if len(releases) > 0 {
+			verList = releases
+		}
+	}
+
+	if len(verList) == 0 {
+		// No branch/tag with that version. If we wanted v0 then we can just
+		// use the master branch.
+		if scheme.Line(v) == 0 {
+			return master, master != nil
+		}
+		return nil, false
+	}
+
+	// Sort the version list. If it contains multiple refs with the same
+	// version (e.g. a tag and branch, or tags differing only in build
+	// metadata), the sort order places the preferred one first.
+	sort.Sort(sort.Reverse(refsByScheme{verList, scheme}))
+	return verList[0].gitRef, true
+}
+
+// chooseRefConstraint chooses the ref in the list with the greatest version
+// satisfying the given constraint. It returns ok=false if no ref satisfies the
+// constraint.
+//
+// The greatest version is chosen by Constraint.Best using the handler's scheme,
+// and ties between refs of the same version are broken as in chooseRef.
+func (h *Handler) chooseRefConstraint(refs []*gitRef, c *Constraint) (chosen *gitRef, ok bool) {
+	scheme := h.scheme()
+	all, _ := h.refVersions(refs)
+	versions := make([]Version, len(all))
+	for i, rv := range all {
+		versions[i] = rv.Version
+	}
+	best, ok := c.Best(versions, scheme)
+	if !ok {
+		return nil, false
+	}
+	var verList refsByVersion
+	for _, rv := range all {
+		if scheme.Compare(rv.Version, best) == 0 {
+			verList = append(verList, rv)
+		}
+	}
+	sort.Sort(sort.Reverse(refsByScheme{verList, scheme}))
+	return verList[0].gitRef, true
+}
+
+// chooseRefDefault chooses the ref in the list using the given default version
+// policy. It returns ok=false if no ref could be chosen. The returned version
+// is the one chosen, e.g. v2 for a ref of the v2 major line, or InvalidVersion
+// for a named branch which is not a version.
+func (h *Handler) chooseRefDefault(refs []*gitRef, d DefaultVersion) (chosen *gitRef, v Version, ok bool) {
+	if len(d.branch) > 0 {
+		for _, ref := range refs {
+			if ref.Name == "refs/heads/"+d.branch {
+				v, err := h.scheme().Parse(d.branch)
+				if err != nil {
+					v = InvalidVersion
+				}
+				return ref, v, true
+			}
+		}
+		return nil, InvalidVersion, false
+	}
+
+	// Find the greatest major version having any release.
+	v = ParseVersion("v0")
+	if d.latest {
+		var (
+			scheme = h.scheme()
+			found  bool
+			best   Version
+		)
+		all, _ := h.refVersions(refs)
+		for _, rv := range all {
+			if rv.Version.Unstable || len(rv.Version.PreRelease) > 0 {
+				continue
+			}
+			if !found || scheme.Compare(best, rv.Version) < 0 {
+				best, found = rv.Version, true
+			}
+		}
+		if found {
+			v = Version{Major: best.Major, Minor: -1, Patch: -1}
+		}
+	}
+	chosen, ok = h.chooseRef(refs, v)
+	return chosen, v, ok
+}
+
+// refVersions parses the version of every branch and tag ref in the list using
+// the handler's version scheme, or matches them against its RefPatterns. Refs
+// which are not branches or tags, or whose names are not valid versions, are
+// not returned. The master branch ref (or nil) is returned as well.
+func (h *Handler) refVersions(refs []*gitRef) (verList refsByVersion, master *gitRef) {
+	scheme := h.scheme()
 	for _, ref := range refs {
 		// Trim the head and tags prefix. If the strings have different lengths
 		// then we are certain it is a head or tag string.
@@ -275,54 +629,40 @@ func (h *Handler) chooseRef(refs []*gitRef, v Version) (chosenHash string, ok bo
 			master = ref
 		}
 
+		if len(h.RefPatterns) > 0 {
+			// Use the first matching pattern.
+			for _, p := range h.RefPatterns {
+				if refV, ok := p.MatchRef(ref.Name); ok {
+					verList = append(verList, refVersion{
+						Version: refV,
+						gitRef:  ref,
+					})
+					break
+				}
+			}
+			continue
+		}
+
 		// Parse the version string.
-		var refV Version
+		var (
+			refV Version
+			err  error
+		)
 		if isHead {
 			// A head ref.
-			refV = ParseVersion(head)
+			refV, err = scheme.Parse(head)
 		} else {
 			// A tag ref.
-			refV = ParseVersion(tag)
+			refV, err = scheme.Parse(tag)
 		}
-
-		// Ensure that the major versions (and unstable statuses) match the one
-		// we desire. If they don't then we skip this version.
-		if refV.Major != v.Major || refV.Unstable != v.Unstable {
+		if err != nil {
+			// Not a version branch/tag (e.g. "refs/heads/feature").
 			continue
 		}
-
-		// Add it to the version list for sorting.
 		verList = append(verList, refVersion{
 			Version: refV,
 			gitRef:  ref,
 		})
 	}
-
-	if len(verList) == 0 {
-		// No branch/tag with that version. If we wanted v0 then we can just
-		// use the master branch.
-		if v.Major == 0 {
-			return master.BestHash(), true
-		}
-		return "", false
-	}
-
-	// Sort the version list.
-	sort.Sort(sort.Reverse(verList))
-
-	// What if the version list contains both a tag and branch with the same
-	// version? We always choose the branch. Do this now.
-	if len(verList) >= 2 {
-		if verList[0].Version == verList[1].Version {
-			// The versions are the same. Which is the branch?
-			if strings.HasPrefix(verList[0].Name, "refs/heads") {
-				return verList[0].BestHash(), true
-			}
-
-			// It's the other one then.
-			return verList[1].BestHash(), true
-		}
-	}
-
-	return verList[0].BestHash(), true
+	return
 }
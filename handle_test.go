@@ -4,7 +4,17 @@
 
 package semver
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
 
 var refTestData = map[string]*gitRef{
 	"v0": &gitRef{
@@ -29,13 +39,50 @@ var refTestData = map[string]*gitRef{
 		Name: "refs/heads/v2-unstable",
 		Hash: "007",
 	},
+	"v3.0.0-rc.1": &gitRef{
+		Name: "refs/tags/v3.0.0-rc.1",
+		Hash: "008",
+	},
+	"v3.0.0-rc.2": &gitRef{
+		Name: "refs/tags/v3.0.0-rc.2",
+		Hash: "009",
+	},
+	"v1.3.0-beta.1": &gitRef{
+		Name: "refs/tags/v1.3.0-beta.1",
+		Hash: "010",
+	},
+	"v4.1.0+build.1": &gitRef{
+		Name: "refs/tags/v4.1.0+build.1",
+		Hash: "011",
+	},
+	"v4.1.0+build.2": &gitRef{
+		Name: "refs/tags/v4.1.0+build.2",
+		Hash: "012",
+	},
+	"v4.1.0": &gitRef{
+		Name: "refs/heads/v4.1.0",
+		Hash: "013",
+	},
+	"v4.0.0": &gitRef{
+		Name: "refs/tags/v4.0.0",
+		Hash: "014",
+	},
+}
+
+// bestHash returns the best hash of the given ref, or an empty string for nil.
+func bestHash(r *gitRef) string {
+	if r == nil {
+		return ""
+	}
+	return r.BestHash()
 }
 
 func testChooseRef(t *testing.T, expect, target string, all []*gitRef) {
 	v := ParseVersion(target)
 	want := refTestData[expect]
 	h := &Handler{}
-	chosenHash, ok := h.chooseRef(all, v)
+	chosen, ok := h.chooseRef(all, v)
+	chosenHash := bestHash(chosen)
 	wantOk := len(expect) > 0
 	if ok != wantOk {
 		t.Fatalf("chooseRef returned ok=%t, want ok=%t\n", ok, wantOk)
@@ -109,3 +156,285 @@ func TestChooseRefInvalid(t *testing.T) {
 		refTestData["v0"],
 	})
 }
+
+func TestChooseRefPreReleaseFallback(t *testing.T) {
+	target := "v3"
+	expect := "v3.0.0-rc.2"
+	testChooseRef(t, expect, target, []*gitRef{
+		refTestData["v3.0.0-rc.2"],
+		refTestData["v1.2"],
+		refTestData["v3.0.0-rc.1"],
+		refTestData["v0"],
+	})
+}
+
+func TestChooseRefPreferRelease(t *testing.T) {
+	target := "v1"
+	expect := "v1.2"
+	testChooseRef(t, expect, target, []*gitRef{
+		refTestData["v1.3.0-beta.1"],
+		refTestData["v1.2"],
+		refTestData["v1"],
+		refTestData["v0"],
+	})
+}
+
+func TestChooseRefPreRelease(t *testing.T) {
+	target := "v1-beta"
+	expect := "v1.3.0-beta.1"
+	testChooseRef(t, expect, target, []*gitRef{
+		refTestData["v1.3.0-beta.1"],
+		refTestData["v1.2"],
+		refTestData["v1"],
+		refTestData["v0"],
+	})
+}
+
+func TestChooseRefMetadata(t *testing.T) {
+	target := "v4"
+	expect := "v4.1.0+build.2"
+	testChooseRef(t, expect, target, []*gitRef{
+		refTestData["v4.1.0+build.2"],
+		refTestData["v4.0.0"],
+		refTestData["v4.1.0+build.1"],
+	})
+	testChooseRef(t, expect, target, []*gitRef{
+		refTestData["v4.1.0+build.1"],
+		refTestData["v4.0.0"],
+		refTestData["v4.1.0+build.2"],
+	})
+}
+
+func TestChooseRefBranchOverTag(t *testing.T) {
+	target := "v4"
+	expect := "v4.1.0"
+	testChooseRef(t, expect, target, []*gitRef{
+		refTestData["v4.1.0+build.1"],
+		refTestData["v4.1.0"],
+		refTestData["v4.1.0+build.2"],
+		refTestData["v4.0.0"],
+	})
+}
+
+func testChooseRefConstraint(t *testing.T, expect, constraint string, all []*gitRef) {
+	c := MustParseConstraint(constraint)
+	h := &Handler{}
+	chosen, ok := h.chooseRefConstraint(all, c)
+	chosenHash := bestHash(chosen)
+	wantOk := len(expect) > 0
+	if ok != wantOk {
+		t.Fatalf("chooseRefConstraint(%q) returned ok=%t, want ok=%t\n", constraint, ok, wantOk)
+	}
+	if !wantOk {
+		return
+	}
+	if want := refTestData[expect]; chosenHash != want.BestHash() {
+		t.Logf("%q\n", constraint)
+		t.Logf("got %q\n", chosenHash)
+		t.Fatalf("expected %q\n", want.BestHash())
+	}
+}
+
+func TestChooseRefConstraint(t *testing.T) {
+	all := []*gitRef{
+		refTestData["v0"],
+		refTestData["v1.0.1"],
+		refTestData["v1"],
+		refTestData["v1.2"],
+		refTestData["v1.3.0-beta.1"],
+		refTestData["v2-unstable"],
+		refTestData["v4.0.0"],
+		refTestData["v4.1.0+build.1"],
+		refTestData["v4.1.0+build.2"],
+	}
+	testChooseRefConstraint(t, "v1.2", "^1", all)
+	testChooseRefConstraint(t, "v1.0.1", "~1.0", all)
+	testChooseRefConstraint(t, "v1.3.0-beta.1", ">=1.3.0-beta <1.4", all)
+	testChooseRefConstraint(t, "v4.1.0+build.2", ">=1.2 <2.0 || 4.x", all)
+	testChooseRefConstraint(t, "v4.0.0", "4.0", all)
+	testChooseRefConstraint(t, "", "^2", all)
+}
+
+func TestChooseRefOrdering(t *testing.T) {
+	all := []*gitRef{
+		refTestData["v1.2"],
+		refTestData["v2-unstable"],
+		refTestData["v1.0.1"],
+	}
+	c := MustParseConstraint("2.0.0-unstable || 1.x")
+	for _, tst := range []struct {
+		o      Ordering
+		expect string
+	}{
+		{LegacyOrdering, "v1.2"},
+		{SpecOrdering, "v2-unstable"},
+	} {
+		h := &Handler{Ordering: tst.o}
+		chosen, ok := h.chooseRefConstraint(all, c)
+		chosenHash := bestHash(chosen)
+		if want := refTestData[tst.expect].BestHash(); !ok || chosenHash != want {
+			t.Logf("%v\n", tst.o)
+			t.Fatalf("got %q (ok=%t) expected %q\n", chosenHash, ok, want)
+		}
+	}
+}
+
+func TestChooseRefSubdir(t *testing.T) {
+	all := subdirRefs([]*gitRef{
+		refTestData["v0"],
+		refTestData["v1.2"],
+		{Name: "refs/tags/lint/v1.0.1", Hash: "101"},
+		{Name: "refs/tags/lint/v1.1.0", Hash: "102", PeeledHash: "103"},
+		{Name: "refs/tags/lint/v2.0.0", Hash: "104"},
+		{Name: "refs/tags/vet/v1.5.0", Hash: "105"},
+		{Name: "refs/tags/lint/extra/v1.9.0", Hash: "106"},
+		{Name: "refs/heads/lint/v1.8.0", Hash: "107"},
+	}, "lint")
+	h := &Handler{}
+	for _, tst := range []struct {
+		target, expect string
+	}{
+		{"v0", "001"},
+		{"v1", "103"},
+		{"v2", "104"},
+		{"v3", ""},
+	} {
+		chosen, ok := h.chooseRef(all, ParseVersion(tst.target))
+		chosenHash := bestHash(chosen)
+		if ok != (len(tst.expect) > 0) || chosenHash != tst.expect {
+			t.Logf("%s\n", tst.target)
+			t.Fatalf("got %q (ok=%t) expected %q\n", chosenHash, ok, tst.expect)
+		}
+	}
+}
+
+// fileTransport is a http.RoundTripper serving the files in the map for the
+// corresponding request URLs, and a 404 for all other URLs.
+type fileTransport map[string]string
+
+func (f fileTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp := &http.Response{
+		Status:     "404 Not Found",
+		StatusCode: http.StatusNotFound,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    r,
+	}
+	file, ok := f[r.URL.String()]
+	if !ok {
+		return resp, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	resp.Status = "200 OK"
+	resp.StatusCode = http.StatusOK
+	resp.Body = ioutil.NopCloser(strings.NewReader(string(data)))
+	return resp, nil
+}
+
+// blockingTransport is a http.RoundTripper which blocks until the request is
+// canceled.
+type blockingTransport struct{}
+
+func (blockingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	<-r.Context().Done()
+	return nil, r.Context().Err()
+}
+
+type testContextKey struct{}
+
+// Tests that the request context is given to context matchers and upstream
+// requests, and that the upstream timeout is applied.
+func TestHandleContext(t *testing.T) {
+	var matchCtx context.Context
+	h := &Handler{
+		Host: "example.com",
+		Matcher: ContextMatcherFunc(func(ctx context.Context, u *url.URL) (*Repo, error) {
+			matchCtx = ctx
+			return GitHub("bob").Match(u)
+		}),
+		Client:          &http.Client{Transport: blockingTransport{}},
+		UpstreamTimeout: 10 * time.Millisecond,
+	}
+	ctx := context.WithValue(context.Background(), testContextKey{}, "value")
+	r, _ := http.NewRequest("GET", "https://example.com/pkg.v1?go-get=1", nil)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+	if s, err := h.Handle(w, r); s != Handled || err != nil || w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("got status %v err %v code %d\n", s, err, w.Code)
+	}
+	if matchCtx == nil || matchCtx.Value(testContextKey{}) != "value" {
+		t.Fatal("matcher not given the request context")
+	}
+
+	// A canceled request is not a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h.UpstreamTimeout = 0
+	w = httptest.NewRecorder()
+	if s, err := h.Handle(w, r.WithContext(ctx)); s != Handled || err != nil || w.Code != http.StatusBadGateway {
+		t.Fatalf("got status %v err %v code %d\n", s, err, w.Code)
+	}
+
+	// A matcher exceeding the timeout, returning the error wrapped.
+	h.Matcher = ContextMatcherFunc(func(ctx context.Context, u *url.URL) (*Repo, error) {
+		<-ctx.Done()
+		return nil, fmt.Errorf("matching %s: %w", u, ctx.Err())
+	})
+	h.UpstreamTimeout = 10 * time.Millisecond
+	w = httptest.NewRecorder()
+	if s, err := h.Handle(w, r); s != Handled || err != nil || w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("got status %v err %v code %d\n", s, err, w.Code)
+	}
+
+	// Plain matchers are not invoked once the context is done.
+	m := WithContext(MatcherFunc(func(u *url.URL) (*Repo, error) {
+		t.Fatal("matcher invoked with done context")
+		return nil, nil
+	}))
+	if _, err := m.MatchContext(ctx, &url.URL{Path: "/pkg.v1"}); err != context.Canceled {
+		t.Fatal("want context.Canceled, got", err)
+	}
+}
+
+func TestChooseRefDefault(t *testing.T) {
+	all := []*gitRef{
+		refTestData["v0"],
+		refTestData["v1.2"],
+		refTestData["v2-unstable"],
+		refTestData["v3.0.0-rc.1"],
+		refTestData["v4.0.0"],
+		refTestData["v4.1.0+build.1"],
+	}
+	h := &Handler{}
+	for _, tst := range []struct {
+		d             DefaultVersion
+		refs          []*gitRef
+		expect, major string
+	}{
+		{DefaultLatest, all, "v4.1.0+build.1", "v4"},
+		{DefaultLatest, all[:4], "v1.2", "v1"},
+		{DefaultLatest, []*gitRef{refTestData["v0"], refTestData["v3.0.0-rc.1"]}, "v0", "v0"},
+		{DefaultV0, all, "v0", "v0"},
+		{DefaultBranch("v2-unstable"), all, "v2-unstable", "v2-unstable"},
+		{DefaultBranch("master"), all, "v0", ""},
+		{DefaultBranch("main"), all, "", ""},
+	} {
+		chosen, v, ok := h.chooseRefDefault(tst.refs, tst.d)
+		if !ok {
+			if len(tst.expect) > 0 {
+				t.Fatalf("%v: got ok=false\n", tst.d)
+			}
+			continue
+		}
+		major := ""
+		if v.Major >= 0 {
+			major = v.String()
+		}
+		if want := refTestData[tst.expect].BestHash(); chosen.BestHash() != want || major != tst.major {
+			t.Logf("%v\n", tst.d)
+			t.Fatalf("got %q %q, expected %q %q\n", chosen.BestHash(), major, want, tst.major)
+		}
+	}
+}
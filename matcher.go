@@ -5,7 +5,11 @@
 package semver
 
 import (
+	"context"
 	"net/url"
+	"path"
+	"sort"
+	"strings"
 )
 
 // Matcher defines an object responsible for matching any given URL to an
@@ -34,3 +38,176 @@ type MatcherFunc func(u *url.URL) (r *Repo, err error)
 func (m MatcherFunc) Match(u *url.URL) (r *Repo, err error) {
 	return m(u)
 }
+
+// ContextMatcher is implemented by matchers which take a context, e.g. because
+// they perform requests to other servers. If the Matcher of a Handler
+// implements this interface, MatchContext is used instead of Match, with the
+// context of the HTTP request being handled. The matchers returned by First,
+// Prefix and Rewrite implement it, and pass the context on.
+type ContextMatcher interface {
+	// MatchContext is like Match, except that the given context should be
+	// used for any outgoing requests. Once the context is done, the context's
+	// error (e.g. context.DeadlineExceeded) should be returned as soon as
+	// possible.
+	MatchContext(ctx context.Context, u *url.URL) (r *Repo, err error)
+}
+
+// ContextMatcherFunc implements both the Matcher and ContextMatcher interfaces
+// by simply invoking the function. Match invokes it with the background
+// context.
+type ContextMatcherFunc func(ctx context.Context, u *url.URL) (r *Repo, err error)
+
+// Match simply invokes the function, m, with the background context.
+func (m ContextMatcherFunc) Match(u *url.URL) (r *Repo, err error) {
+	return m(context.Background(), u)
+}
+
+// MatchContext simply invokes the function, m.
+func (m ContextMatcherFunc) MatchContext(ctx context.Context, u *url.URL) (r *Repo, err error) {
+	return m(ctx, u)
+}
+
+// WithContext adapts the given matcher to the ContextMatcher interface. If it
+// implements the interface already, it is returned as-is. Otherwise the context
+// is only checked before invoking its Match method.
+func WithContext(m Matcher) ContextMatcher {
+	if cm, ok := m.(ContextMatcher); ok {
+		return cm
+	}
+	return ContextMatcherFunc(func(ctx context.Context, u *url.URL) (*Repo, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return m.Match(u)
+	})
+}
+
+// matchContext matches the URL using the given matcher, passing the context on
+// if it is a ContextMatcher.
+func matchContext(ctx context.Context, m Matcher, u *url.URL) (*Repo, error) {
+	return WithContext(m).MatchContext(ctx, u)
+}
+
+// First returns a Matcher which tries each of the given matchers in order, and
+// returns the result of the first one that does not return ErrNotPackageURL.
+// Any other error (e.g. a *HTTPError) is returned as-is, without trying the
+// remaining matchers. If no matcher matches, ErrNotPackageURL is returned.
+func First(m ...Matcher) Matcher {
+	return ContextMatcherFunc(func(ctx context.Context, u *url.URL) (*Repo, error) {
+		for _, matcher := range m {
+			r, err := matchContext(ctx, matcher, u)
+			if err == ErrNotPackageURL {
+				continue
+			}
+			return r, err
+		}
+		return nil, ErrNotPackageURL
+	})
+}
+
+// Prefix returns a Matcher which routes URLs to the matcher of the longest
+// matching path prefix in the map, for example with the routes:
+//
+//  "gh":       semver.GitHub("bob")
+//  "gh/alice": semver.GitHub("alice")
+//  "lab":      semver.GitLab("gitlab.com", "bob")
+//
+// It would match URLS like so:
+//
+//  example.com/gh/pkg.v1 → github.com/bob/pkg
+//  example.com/gh/alice/pkg.v1/subpkg → github.com/alice/pkg
+//  example.com/lab/team/pkg.v1 → gitlab.com/bob/team/pkg
+//
+// Prefixes match whole path elements, and the empty prefix matches all URLs.
+// The prefix is stripped from the URL path before it is given to the matcher,
+// and the go-source meta tag of the repository is fixed to use the full import
+// path. URLs not matching any prefix result in ErrNotPackageURL.
+func Prefix(routes map[string]Matcher) Matcher {
+	prefixes := make([]string, 0, len(routes))
+	matchers := make(map[string]Matcher, len(routes))
+	for prefix, m := range routes {
+		prefix = strings.Trim(prefix, "/")
+		prefixes = append(prefixes, prefix)
+		matchers[prefix] = m
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+	return ContextMatcherFunc(func(ctx context.Context, u *url.URL) (*Repo, error) {
+		rel := strings.TrimPrefix(u.Path, "/")
+		for _, prefix := range prefixes {
+			if len(prefix) > 0 && rel != prefix && !strings.HasPrefix(rel, prefix+"/") {
+				continue
+			}
+			stripped := *u
+			stripped.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(rel, prefix), "/")
+			r, err := matchContext(ctx, matchers[prefix], &stripped)
+			return fixGoSource(u, r, err)
+		}
+		return nil, ErrNotPackageURL
+	})
+}
+
+// Rewrite returns a Matcher which rewrites URLs using the given function
+// before giving them to the matcher. If the function returns nil, the URL is
+// not a package URL and ErrNotPackageURL is returned. The function must not
+// modify the given URL.
+//
+// The SubPath of the repository should be the same in the original and in the
+// rewritten URL, as it is relative to the original URL by the Handler. The
+// go-source meta tag of the repository is fixed to use the original import
+// path.
+func Rewrite(fn func(u *url.URL) *url.URL, m Matcher) Matcher {
+	return ContextMatcherFunc(func(ctx context.Context, u *url.URL) (*Repo, error) {
+		rewritten := fn(u)
+		if rewritten == nil {
+			return nil, ErrNotPackageURL
+		}
+		r, err := matchContext(ctx, m, rewritten)
+		return fixGoSource(u, r, err)
+	})
+}
+
+// fixGoSource fixes the go-source meta tag of the repository returned by a
+// matcher given a different URL than the original one, u, such that it uses
+// the import path of the repository root in the original URL.
+func fixGoSource(u *url.URL, r *Repo, err error) (*Repo, error) {
+	if err != nil || r == nil {
+		return r, err
+	}
+	if r.Fallback != nil {
+		fixGoSource(u, r.Fallback, nil)
+	}
+	r.SubPath = strings.Trim(r.SubPath, "/")
+
+	// The SubPath must match whole path elements at the end of the original
+	// URL, or the original URL does not hold the repository root.
+	root := u.Path
+	switch {
+	case len(r.SubPath) == 0:
+	case root == r.SubPath:
+		root = ""
+	case strings.HasSuffix(root, "/"+r.SubPath):
+		root = strings.TrimSuffix(root, "/"+r.SubPath)
+	default:
+		return r, nil
+	}
+	prefix := path.Join(u.Host, root)
+	r.GoSource = replaceGoSourcePrefix(r.GoSource, prefix)
+	if fn := r.GoSourceFunc; fn != nil {
+		r.GoSourceFunc = func(r *Repo) string {
+			return replaceGoSourcePrefix(fn(r), prefix)
+		}
+	}
+	return r, nil
+}
+
+// replaceGoSourcePrefix replaces the import path prefix (the first field) of
+// the given go-source meta tag content.
+func replaceGoSourcePrefix(goSource, prefix string) string {
+	i := strings.IndexByte(goSource, ' ')
+	if i == -1 {
+		return goSource
+	}
+	return prefix + goSource[i:]
+}
@@ -0,0 +1,166 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Tests the First matcher combinator.
+func TestFirst(t *testing.T) {
+	calls := 0
+	never := MatcherFunc(func(u *url.URL) (*Repo, error) {
+		calls++
+		return nil, ErrNotPackageURL
+	})
+	m := First(never, GitLab("gitlab.com", "bob"), GitHub("bob"))
+	u, _ := url.Parse("https://example.com/team/pkg.v1")
+	repo, err := m.Match(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.URL.Host != "gitlab.com" || calls != 1 {
+		t.Fatalf("got host %q after %d calls\n", repo.URL.Host, calls)
+	}
+
+	// HTTP errors are returned without trying the next matchers.
+	u, _ = url.Parse("https://example.com/pkg.v1.2")
+	if _, err := m.Match(u); err == nil {
+		t.Fatal("want *HTTPError, got nil")
+	} else if _, ok := err.(*HTTPError); !ok {
+		t.Fatal("want *HTTPError, got", err)
+	}
+
+	u, _ = url.Parse("https://example.com/pkg")
+	if _, err := m.Match(u); err != ErrNotPackageURL {
+		t.Fatal("want ErrNotPackageURL, got", err)
+	}
+}
+
+var prefixTests = []struct {
+	url, host, path, subpath, goSourcePrefix string
+}{
+	{"https://example.com/gh/pkg.v1", "github.com", "bob/pkg.git", "", "example.com/gh/pkg.v1"},
+	{"https://example.com/gh/alice/pkg.v1/subpkg", "github.com", "alice/pkg.git", "subpkg", "example.com/gh/alice/pkg.v1"},
+	{"https://example.com/gh/alicex/pkg.v1", "github.com", "bob/alicex-pkg.git", "", "example.com/gh/alicex/pkg.v1"},
+	{"https://example.com/lab/team/pkg.v2/a/b", "gitlab.com", "bob/team/pkg.git", "a/b", "example.com/lab/team/pkg.v2"},
+	{"https://example.com/pkg.v1", "", "", "", ""},
+	{"https://example.com/ghx/pkg.v1", "", "", "", ""},
+}
+
+// Tests the Prefix matcher combinator.
+func TestPrefix(t *testing.T) {
+	m := Prefix(map[string]Matcher{
+		"gh":        GitHub("bob"),
+		"/gh/alice": GitHub("alice"),
+		"lab/":      GitLab("gitlab.com", "bob"),
+	})
+	for _, tst := range prefixTests {
+		u, err := url.Parse(tst.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo, err := m.Match(u)
+		if len(tst.host) == 0 {
+			if err != ErrNotPackageURL {
+				t.Log(u)
+				t.Fatal("want ErrNotPackageURL, got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Log(u)
+			t.Fatal("Test is valid but matcher returned:", err)
+		}
+		if repo.URL.Host != tst.host || repo.URL.Path != tst.path || repo.SubPath != tst.subpath {
+			t.Log(u)
+			t.Logf("want %q %q %q\n", tst.host, tst.path, tst.subpath)
+			t.Logf("got %q %q %q\n", repo.URL.Host, repo.URL.Path, repo.SubPath)
+			t.Fatal("incorrect repo")
+		}
+		if !strings.HasPrefix(repo.GoSource, tst.goSourcePrefix+" ") {
+			t.Log(u)
+			t.Log("want prefix", tst.goSourcePrefix)
+			t.Log("got", repo.GoSource)
+			t.Fatal("incorrect go-source")
+		}
+	}
+}
+
+// Tests the Rewrite matcher combinator.
+func TestRewrite(t *testing.T) {
+	// Serve "example.com/x/pkg" as "pkg.v1".
+	m := Rewrite(func(u *url.URL) *url.URL {
+		rel := strings.TrimPrefix(u.Path, "/x/")
+		if len(rel) == len(u.Path) {
+			return nil
+		}
+		elems := strings.SplitN(rel, "/", 2)
+		elems[0] += ".v1"
+		cpy := *u
+		cpy.Path = "/" + strings.Join(elems, "/")
+		return &cpy
+	}, Gitea("git.example.com", "bob"))
+
+	u, _ := url.Parse("https://example.com/x/pkg/subpkg")
+	repo, err := m.Match(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.URL.Path != "bob/pkg.git" || repo.SubPath != "subpkg" || repo.Version != ParseVersion("v1") {
+		t.Fatalf("got %q %q %v\n", repo.URL.Path, repo.SubPath, repo.Version)
+	}
+	repo.Ref = "refs/tags/v1.0.0"
+	if got := repo.GoSourceFunc(repo); !strings.HasPrefix(got, "example.com/x/pkg _ ") {
+		t.Fatal("incorrect go-source", got)
+	}
+
+	u, _ = url.Parse("https://example.com/pkg.v1")
+	if _, err := m.Match(u); err != ErrNotPackageURL {
+		t.Fatal("want ErrNotPackageURL, got", err)
+	}
+
+	// The go-source meta tag is left as is if the SubPath only matches part
+	// of a path element of the original URL.
+	m = Rewrite(func(u *url.URL) *url.URL {
+		cpy := *u
+		cpy.Path = "/pkg.v1/ub"
+		return &cpy
+	}, GitHub("bob"))
+	u, _ = url.Parse("https://example.com/y/pkg/sub")
+	repo, err = m.Match(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(repo.GoSource, "example.com/pkg.v1 _ ") {
+		t.Fatal("incorrect go-source", repo.GoSource)
+	}
+}
+
+// Tests that HTTP errors from combined matchers are handled by a Handler.
+func TestCombinedMatcherHTTPError(t *testing.T) {
+	h := &Handler{
+		Host: "example.com",
+		Matcher: First(
+			Prefix(map[string]Matcher{"gh": GitHub("bob")}),
+			Rewrite(func(u *url.URL) *url.URL { return nil }, GitHub("bob")),
+		),
+	}
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://example.com/gh/pkg.v1.2?go-get=1", nil)
+	if s, err := h.Handle(w, r); s != Handled || err != nil || w.Code != http.StatusNotFound {
+		t.Fatalf("got status %v err %v code %d\n", s, err, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "https://example.com/pkg.v1?go-get=1", nil)
+	if s, err := h.Handle(w, r); s != Unhandled || err != nil {
+		t.Fatalf("got status %v err %v\n", s, err)
+	}
+}
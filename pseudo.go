@@ -0,0 +1,197 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"strings"
+	"time"
+)
+
+// pseudoTimeFormat is the time format of the timestamp in a pseudo-version.
+const pseudoTimeFormat = "20060102150405"
+
+// pseudoRevLen is the length to which revisions are shortened in a
+// pseudo-version, as the go command does.
+const pseudoRevLen = 12
+
+// PseudoVersion returns a Go module pseudo-version for a commit with the given
+// commit time and revision (commit hash) on top of the given base version, the
+// most recent tagged version before the commit. It is one of three forms:
+//
+//  vX.0.0-yyyymmddhhmmss-abcdefabcdef
+//  vX.Y.Z-pre.0.yyyymmddhhmmss-abcdefabcdef
+//  vX.Y.(Z+1)-0.yyyymmddhhmmss-abcdefabcdef
+//
+// The first form is used when there is no base version, which is indicated by
+// a base version with only a major version number (e.g. ParseVersion("v0") for
+// a v0 or v1 module, or ParseVersion("v2") for a v2 module). The second form is
+// used when the base version is a pre-release (or unstable) version, and the
+// third form otherwise. A base version without a patch version number is
+// treated as having a zero patch version (e.g. "v1.2" as "v1.2.0").
+//
+// The commit time is written in UTC and the revision is shortened to twelve
+// characters, as the go command does. Any build metadata of the base version
+// (e.g. "+incompatible") is kept. The pseudo-version of an invalid base version
+// is InvalidVersion.
+func PseudoVersion(base Version, t time.Time, rev string) Version {
+	if len(rev) > pseudoRevLen {
+		rev = rev[:pseudoRevLen]
+	}
+	suffix := t.UTC().Format(pseudoTimeFormat) + "-" + rev
+
+	switch base.Precision() {
+	case 0:
+		return InvalidVersion
+	case 1:
+		return Version{
+			Major:      base.Major,
+			Minor:      0,
+			Patch:      0,
+			PreRelease: suffix,
+			Metadata:   base.Metadata,
+		}
+	case 2:
+		base.Patch = 0
+	}
+	if pre := base.preRelease(); len(pre) > 0 {
+		v := base.Release()
+		v.PreRelease = pre + ".0." + suffix
+		v.Metadata = base.Metadata
+		return v
+	}
+	v := base.IncPatch()
+	v.PreRelease = "0." + suffix
+	v.Metadata = base.Metadata
+	return v
+}
+
+// pseudoVersion returns the Go module pseudo-version of this ref on top of the
+// given base version, given the commit time. It is the version the go command
+// resolves the ref to, if the ref is a branch head. See PseudoVersion.
+func (r *gitRef) pseudoVersion(base Version, t time.Time) Version {
+	return PseudoVersion(base, t, r.BestHash())
+}
+
+// PseudoVersion returns the Go module pseudo-version of the branch chosen for
+// the repository by the Handler (see Ref), on top of the given base version and
+// given the commit time of the branch head, as the go command would resolve the
+// branch to (e.g. in a GoSourceFunc). It returns ok=false if no ref was chosen
+// yet, or if the chosen ref is a tag.
+func (r *Repo) PseudoVersion(base Version, t time.Time) (v Version, ok bool) {
+	if r.chosen == nil || !strings.HasPrefix(r.Ref, "refs/heads/") {
+		return InvalidVersion, false
+	}
+	return r.chosen.pseudoVersion(base, t), true
+}
+
+// pseudoSuffix returns the index in the pre-release identifiers of the
+// pseudo-version "yyyymmddhhmmss-abcdefabcdef" identifier, or -1 if this is
+// not a pseudo-version.
+func (v Version) pseudoSuffix() int {
+	if v.Precision() != 3 || v.Unstable {
+		return -1
+	}
+	ids := v.PreReleaseIdentifiers()
+	if len(ids) == 0 || !isPseudoSuffix(ids[len(ids)-1]) {
+		return -1
+	}
+	last := len(ids) - 1
+	if last == 0 {
+		// vX.0.0-yyyymmddhhmmss-abcdefabcdef
+		if v.Minor != 0 || v.Patch != 0 {
+			return -1
+		}
+		return last
+	}
+	// vX.Y.Z-pre.0.yyyymmddhhmmss-abcdefabcdef or
+	// vX.Y.(Z+1)-0.yyyymmddhhmmss-abcdefabcdef
+	if ids[last-1] != "0" {
+		return -1
+	}
+	return last
+}
+
+// isPseudoSuffix tells if the identifier s is in the form of
+// "yyyymmddhhmmss-abcdefabcdef".
+func isPseudoSuffix(s string) bool {
+	i := strings.IndexByte(s, '-')
+	if i != len(pseudoTimeFormat) || !isNumeric(s[:i]) || len(s) == i+1 {
+		return false
+	}
+	for _, c := range s[i+1:] {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPseudo tells if this version is a Go module pseudo-version, like one
+// returned by PseudoVersion.
+func (v Version) IsPseudo() bool {
+	return v.pseudoSuffix() != -1
+}
+
+// PseudoTime returns the commit time of a pseudo-version, in UTC. It returns
+// ok=false if this is not a pseudo-version or the time is not valid.
+func (v Version) PseudoTime() (t time.Time, ok bool) {
+	i := v.pseudoSuffix()
+	if i == -1 {
+		return time.Time{}, false
+	}
+	ts := v.PreReleaseIdentifiers()[i][:len(pseudoTimeFormat)]
+	t, err := time.Parse(pseudoTimeFormat, ts)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// PseudoRev returns the (shortened) revision of a pseudo-version, or an empty
+// string if this is not a pseudo-version.
+func (v Version) PseudoRev() string {
+	i := v.pseudoSuffix()
+	if i == -1 {
+		return ""
+	}
+	return v.PreReleaseIdentifiers()[i][len(pseudoTimeFormat)+1:]
+}
+
+// PseudoBase returns the base version of a pseudo-version, that is the inverse
+// of PseudoVersion:
+//
+//  v1.0.0-20191109021931-daa7c04131f5        -> v1
+//  v1.2.3-pre.0.20191109021931-daa7c04131f5  -> v1.2.3-pre
+//  v1.2.4-0.20191109021931-daa7c04131f5      -> v1.2.3
+//
+// Build metadata is kept. It returns ok=false if this is not a pseudo-version.
+func (v Version) PseudoBase() (base Version, ok bool) {
+	i := v.pseudoSuffix()
+	if i == -1 {
+		return InvalidVersion, false
+	}
+	ids := v.PreReleaseIdentifiers()
+	base = v
+	switch {
+	case i == 0:
+		base.Minor, base.Patch = -1, -1
+		base.PreRelease = ""
+	case i == 1:
+		if v.Patch == 0 {
+			// Not a pseudo-version produced by the go command, there is no
+			// release before vX.Y.0.
+			return InvalidVersion, false
+		}
+		base.Patch--
+		base.PreRelease = ""
+	default:
+		base.PreRelease = strings.Join(ids[:i-1], ".")
+		if base.PreRelease == "unstable" {
+			base.PreRelease = ""
+			base.Unstable = true
+		}
+	}
+	return base, true
+}
@@ -0,0 +1,159 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"testing"
+	"time"
+)
+
+var pseudoTime = time.Date(2019, 11, 9, 2, 19, 31, 0, time.UTC)
+
+var pseudoVersionTests = []struct {
+	base, pseudo string
+}{
+	{"v0", "v0.0.0-20191109021931-daa7c04131f5"},
+	{"v2", "v2.0.0-20191109021931-daa7c04131f5"},
+	{"v1.2", "v1.2.1-0.20191109021931-daa7c04131f5"},
+	{"v1.2.3", "v1.2.4-0.20191109021931-daa7c04131f5"},
+	{"v1.2.3-pre", "v1.2.3-pre.0.20191109021931-daa7c04131f5"},
+	{"v1.2.3-rc.1", "v1.2.3-rc.1.0.20191109021931-daa7c04131f5"},
+	{"v1.2.3-unstable", "v1.2.3-unstable.0.20191109021931-daa7c04131f5"},
+	{"v2.0.0+incompatible", "v2.0.1-0.20191109021931-daa7c04131f5+incompatible"},
+}
+
+func TestPseudoVersion(t *testing.T) {
+	for _, tst := range pseudoVersionTests {
+		base := ParseVersion(tst.base)
+		rev := "daa7c04131f5e3f3a0c2f4e3b7d9a4c5f1e2d3c4"
+		got := PseudoVersion(base, pseudoTime.In(time.FixedZone("X", 3600)), rev)
+		if got.String() != tst.pseudo {
+			t.Logf("%q\n", tst.base)
+			t.Fatalf("got %q want %q\n", got, tst.pseudo)
+		}
+		if !got.IsPseudo() {
+			t.Fatalf("%q: IsPseudo() = false\n", got)
+		}
+		if pt, ok := got.PseudoTime(); !ok || !pt.Equal(pseudoTime) {
+			t.Fatalf("%q: PseudoTime() got %v (ok=%t) want %v\n", got, pt, ok, pseudoTime)
+		}
+		if r := got.PseudoRev(); r != "daa7c04131f5" {
+			t.Fatalf("%q: PseudoRev() got %q\n", got, r)
+		}
+
+		// The base of a pseudo-version without a base version is the major
+		// version only, a missing patch version of the base is zero.
+		wantBase := base
+		switch base.Precision() {
+		case 1:
+			wantBase = Version{Major: base.Major, Minor: -1, Patch: -1}
+		case 2:
+			wantBase.Patch = 0
+		}
+		if b, ok := got.PseudoBase(); !ok || b != wantBase {
+			t.Fatalf("%q: PseudoBase() got %q (ok=%t) want %q\n", got, b, ok, wantBase)
+		}
+
+		// Parsing must round-trip.
+		if p := ParseVersion(tst.pseudo); p != got {
+			t.Fatalf("ParseVersion(%q) got %#v want %#v\n", tst.pseudo, p, got)
+		}
+	}
+	if PseudoVersion(InvalidVersion, pseudoTime, "abc") != InvalidVersion {
+		t.Fatal("want InvalidVersion for invalid base")
+	}
+}
+
+func TestNotPseudoVersion(t *testing.T) {
+	for _, vs := range []string{
+		"v1.2.3",
+		"v1.2.3-rc.1",
+		"v1.2.0-20191109021931-daa7c04131f5",
+		"v1.2.3-1.20191109021931-daa7c04131f5",
+		"v1.2.3-0.2019110902193-daa7c04131f5",
+		"v1.2.3-0.20191109021931-",
+		"v1.2-0.20191109021931-daa7c04131f5",
+		"v1.2.0-0.20191109021931-daa7c04131f5",
+	} {
+		v := ParseVersion(vs)
+		if _, ok := v.PseudoBase(); ok {
+			t.Fatalf("%q: PseudoBase() returned ok=true\n", vs)
+		}
+		if vs == "v1.2.0-0.20191109021931-daa7c04131f5" {
+			// Valid pseudo-version syntax, but without a possible base.
+			continue
+		}
+		if v.IsPseudo() || v.PseudoRev() != "" {
+			t.Fatalf("%q: IsPseudo() = true\n", vs)
+		}
+		if _, ok := v.PseudoTime(); ok {
+			t.Fatalf("%q: PseudoTime() returned ok=true\n", vs)
+		}
+	}
+}
+
+func TestPseudoVersionOrdering(t *testing.T) {
+	// Pseudo-versions sort after their base version, and before any later
+	// release or pre-release.
+	sorted := parseVersions(`
+		v0.0.0-20191109021931-daa7c04131f5
+		v0.0.0-20191110000000-0123456789ab
+		v1.2.3-pre
+		v1.2.3-pre.0.20191109021931-daa7c04131f5
+		v1.2.3-pre.1
+		v1.2.3
+		v1.2.4-0.20191109021931-daa7c04131f5
+		v1.2.4-0.20191110000000-0123456789ab
+		v1.2.4-rc.1
+		v1.2.4
+	`)
+	for _, o := range []Ordering{LegacyOrdering, SpecOrdering} {
+		for i := 0; i+1 < len(sorted); i++ {
+			if !o.Less(sorted[i], sorted[i+1]) {
+				t.Fatalf("%v: want %q < %q\n", o, sorted[i], sorted[i+1])
+			}
+		}
+	}
+}
+
+func TestGitRefPseudoVersion(t *testing.T) {
+	ref := &gitRef{
+		Name:       "refs/heads/master",
+		Hash:       "1111111111111111111111111111111111111111",
+		PeeledHash: "daa7c04131f5e3f3a0c2f4e3b7d9a4c5f1e2d3c4",
+	}
+	got := ref.pseudoVersion(ParseVersion("v1.2.3"), pseudoTime)
+	if want := "v1.2.4-0.20191109021931-daa7c04131f5"; got.String() != want {
+		t.Fatalf("got %q want %q\n", got, want)
+	}
+}
+
+// Tests the pseudo-version of the branch chosen for a repository.
+func TestRepoPseudoVersion(t *testing.T) {
+	refs := []*gitRef{
+		{Name: "refs/heads/master", Hash: "daa7c04131f5e3f3a0c2f4e3b7d9a4c5f1e2d3c4"},
+		{Name: "refs/tags/v1.0.0", Hash: "2222222222222222222222222222222222222222"},
+	}
+	h := &Handler{}
+	for _, tst := range []struct {
+		version, pseudo string
+		ok              bool
+	}{
+		{"v0", "v0.0.0-20191109021931-daa7c04131f5", true},
+		{"v1", "", false},
+	} {
+		repo := &Repo{Version: ParseVersion(tst.version)}
+		if _, ok := repo.PseudoVersion(ParseVersion("v0"), pseudoTime); ok {
+			t.Fatal("want ok=false before a ref is chosen")
+		}
+		if _, ok := h.chooseRepoRef(refs, repo); !ok {
+			t.Fatal("no ref chosen for", tst.version)
+		}
+		got, ok := repo.PseudoVersion(ParseVersion("v0"), pseudoTime)
+		if ok != tst.ok || (ok && got.String() != tst.pseudo) {
+			t.Fatalf("%s: got %q (ok=%t) want %q (ok=%t)\n", tst.version, got, ok, tst.pseudo, tst.ok)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RefPattern maps the names of branches and tags of a repository to versions,
+// see the Handler.RefPatterns field.
+type RefPattern interface {
+	// MatchRef matches the given full ref name (e.g. "refs/tags/release-1.2")
+	// to a version. If the ref name does not match the pattern then ok=false
+	// is returned.
+	MatchRef(name string) (v Version, ok bool)
+}
+
+// refRegexp is a RefPattern using a regular expression with named captures.
+type refRegexp struct {
+	re *regexp.Regexp
+}
+
+// MatchRef implements the RefPattern interface.
+func (p refRegexp) MatchRef(name string) (Version, bool) {
+	m := p.re.FindStringSubmatch(name)
+	if m == nil {
+		return InvalidVersion, false
+	}
+	var (
+		v      = InvalidVersion
+		suffix string
+	)
+	for i, capture := range p.re.SubexpNames() {
+		if len(m[i]) == 0 {
+			continue
+		}
+		switch capture {
+		case "major", "minor", "patch":
+			if !isNumeric(m[i]) {
+				return InvalidVersion, false
+			}
+			n, err := strconv.Atoi(m[i])
+			if err != nil {
+				return InvalidVersion, false
+			}
+			switch capture {
+			case "major":
+				v.Major = n
+			case "minor":
+				v.Minor = n
+			case "patch":
+				v.Patch = n
+			}
+		case "pre":
+			suffix = "-" + m[i] + suffix
+		case "meta":
+			suffix += "+" + m[i]
+		}
+	}
+
+	// Validate the version numbers: a patch version requires a minor version.
+	if v.Major < 0 || v.Minor < 0 && v.Patch >= 0 {
+		return InvalidVersion, false
+	}
+	if i, _ := parseSuffix(suffix, 0, &v); i != -1 {
+		return InvalidVersion, false
+	}
+	return v, true
+}
+
+// RefRegexp returns a RefPattern which matches ref names against the given
+// regular expression. The expression must match the entire ref name, and the
+// version is built from its named captures:
+//
+//  major  the major version number (required)
+//  minor  the minor version number
+//  patch  the patch version number
+//  pre    the pre-release identifiers, or "unstable"
+//  meta   the build metadata
+//
+// Captures which are absent from the expression or do not participate in a
+// match are treated as absent version numbers (or no pre-release/metadata).
+// Ref names are ignored if any capture is not valid (e.g. a major capture that
+// is not numeric). For example, to match "release-1.2" branches:
+//
+//  semver.RefRegexp(`refs/heads/release-(?P<major>\d+)\.(?P<minor>\d+)`)
+//
+func RefRegexp(expr string) (RefPattern, error) {
+	re, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		return nil, err
+	}
+	hasMajor := false
+	for _, name := range re.SubexpNames() {
+		hasMajor = hasMajor || name == "major"
+	}
+	if !hasMajor {
+		return nil, fmt.Errorf("ref pattern %q has no major capture", expr)
+	}
+	return refRegexp{re}, nil
+}
+
+// refTemplatePlaceholders maps the RefTemplate placeholders to their regular
+// expressions.
+var refTemplatePlaceholders = map[string]string{
+	"{major}": `(?P<major>[0-9]+)`,
+	"{minor}": `(?P<minor>[0-9]+)`,
+	"{patch}": `(?P<patch>[0-9]+)`,
+	"{pre}":   `(?P<pre>[0-9A-Za-z.-]+)`,
+	"{meta}":  `(?P<meta>[0-9A-Za-z.-]+)`,
+}
+
+// RefTemplate returns a RefPattern which matches ref names against the given
+// template. The template is a full ref name with placeholders for the parts of
+// the version, "{major}" (required), "{minor}", "{patch}", "{pre}" and
+// "{meta}", see RefRegexp for their meaning. All other text is matched
+// literally. For example:
+//
+//  "refs/heads/release-{major}.{minor}"  matches  "refs/heads/release-1.2"
+//  "refs/tags/{major}.{minor}.{patch}"   matches  "refs/tags/1.2.3"
+//  "refs/heads/v{major}.x"               matches  "refs/heads/v1.x"
+//
+func RefTemplate(tmpl string) (RefPattern, error) {
+	var (
+		expr string
+		seen = make(map[string]bool)
+	)
+	for rest := tmpl; len(rest) > 0; {
+		start := strings.IndexByte(rest, '{')
+		if start == -1 {
+			expr += regexp.QuoteMeta(rest)
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end == -1 {
+			return nil, fmt.Errorf("ref template %q has unterminated placeholder", tmpl)
+		}
+		end += start + 1
+		placeholder := rest[start:end]
+		re, ok := refTemplatePlaceholders[placeholder]
+		if !ok {
+			return nil, fmt.Errorf("ref template %q has unknown placeholder %s", tmpl, placeholder)
+		}
+		if seen[placeholder] {
+			return nil, fmt.Errorf("ref template %q has duplicate placeholder %s", tmpl, placeholder)
+		}
+		seen[placeholder] = true
+		expr += regexp.QuoteMeta(rest[:start]) + re
+		rest = rest[end:]
+	}
+	if !seen["{major}"] {
+		return nil, fmt.Errorf("ref template %q has no {major} placeholder", tmpl)
+	}
+	return RefRegexp(expr)
+}
+
+// MustRefPattern is a helper that wraps a call to a function returning
+// (RefPattern, error) and panics if the error is non-nil. It is intended for
+// use in variable initializations such as:
+//
+//  var p = semver.MustRefPattern(semver.RefTemplate("refs/tags/{major}.{minor}"))
+//
+func MustRefPattern(p RefPattern, err error) RefPattern {
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
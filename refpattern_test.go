@@ -0,0 +1,124 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import "testing"
+
+var refTemplateTests = []struct {
+	tmpl, ref string
+	exp       Version
+	ok        bool
+}{
+	{"refs/heads/release-{major}.{minor}", "refs/heads/release-1.2", Version{Major: 1, Minor: 2, Patch: -1}, true},
+	{"refs/heads/release-{major}.{minor}", "refs/heads/release-1", InvalidVersion, false},
+	{"refs/heads/release-{major}.{minor}", "refs/tags/release-1.2", InvalidVersion, false},
+	{"refs/heads/release-{major}.{minor}", "refs/heads/release-1.2.3", InvalidVersion, false},
+	{"refs/tags/{major}.{minor}.{patch}", "refs/tags/1.2.3", Version{Major: 1, Minor: 2, Patch: 3}, true},
+	{"refs/tags/{major}.{minor}.{patch}", "refs/tags/v1.2.3", InvalidVersion, false},
+	{"refs/heads/v{major}.x", "refs/heads/v1.x", Version{Major: 1, Minor: -1, Patch: -1}, true},
+	{"refs/heads/v{major}.x", "refs/heads/v1.1", InvalidVersion, false},
+	{"refs/tags/{major}.{minor}.{patch}-{pre}", "refs/tags/1.2.3-rc.1", Version{Major: 1, Minor: 2, Patch: 3, PreRelease: "rc.1"}, true},
+	{"refs/tags/{major}.{minor}.{patch}-{pre}", "refs/tags/1.2.3-rc..1", InvalidVersion, false},
+	{"refs/heads/{major}-{pre}", "refs/heads/2-unstable", Version{Major: 2, Minor: -1, Patch: -1, Unstable: true}, true},
+	{"refs/tags/{major}.{minor}.{patch}+{meta}", "refs/tags/1.4.2+20261017.sha.abc123", Version{Major: 1, Minor: 4, Patch: 2, Metadata: "20261017.sha.abc123"}, true},
+	{"refs/tags/build-{meta}-v{major}", "refs/tags/build-7-v3", Version{Major: 3, Minor: -1, Patch: -1, Metadata: "7"}, true},
+	{"refs/tags/(v{major})", "refs/tags/(v3)", Version{Major: 3, Minor: -1, Patch: -1}, true},
+}
+
+func TestRefTemplate(t *testing.T) {
+	for _, tst := range refTemplateTests {
+		p, err := RefTemplate(tst.tmpl)
+		if err != nil {
+			t.Logf("%q\n", tst.tmpl)
+			t.Fatal(err)
+		}
+		v, ok := p.MatchRef(tst.ref)
+		if ok != tst.ok || v != tst.exp {
+			t.Logf("template %q, ref %q\n", tst.tmpl, tst.ref)
+			t.Fatalf("got %#v (ok=%t) want %#v (ok=%t)\n", v, ok, tst.exp, tst.ok)
+		}
+	}
+}
+
+func TestRefTemplateErrors(t *testing.T) {
+	for _, tmpl := range []string{
+		"refs/tags/{minor}",
+		"refs/tags/{major",
+		"refs/tags/{major}.{foo}",
+		"refs/tags/{major}.{major}",
+	} {
+		if _, err := RefTemplate(tmpl); err == nil {
+			t.Fatalf("%q: want error\n", tmpl)
+		}
+	}
+}
+
+func TestRefRegexp(t *testing.T) {
+	p := MustRefPattern(RefRegexp(`refs/(?:heads|tags)/release[-_](?P<major>\d+)(?:\.(?P<minor>\d+))?`))
+	tests := []struct {
+		ref string
+		exp Version
+		ok  bool
+	}{
+		{"refs/heads/release-1.2", Version{Major: 1, Minor: 2, Patch: -1}, true},
+		{"refs/tags/release_3", Version{Major: 3, Minor: -1, Patch: -1}, true},
+		{"refs/tags/release_3.", InvalidVersion, false},
+		{"refs/heads/prerelease-3", InvalidVersion, false},
+	}
+	for _, tst := range tests {
+		v, ok := p.MatchRef(tst.ref)
+		if ok != tst.ok || v != tst.exp {
+			t.Logf("%q\n", tst.ref)
+			t.Fatalf("got %#v (ok=%t) want %#v (ok=%t)\n", v, ok, tst.exp, tst.ok)
+		}
+	}
+
+	// A patch version requires a minor version.
+	p = MustRefPattern(RefRegexp(`refs/tags/(?P<major>\d+)(?:\.(?P<minor>\d+))?(?:-p(?P<patch>\d+))?`))
+	if _, ok := p.MatchRef("refs/tags/1-p2"); ok {
+		t.Fatal("want ok=false for patch without minor")
+	}
+	if _, err := RefRegexp(`refs/tags/(?P<minor>\d+)`); err == nil {
+		t.Fatal("want error for missing major capture")
+	}
+	if _, err := RefRegexp(`refs/tags/(?P<major>\d+`); err == nil {
+		t.Fatal("want error for invalid expression")
+	}
+}
+
+func TestChooseRefPatterns(t *testing.T) {
+	refs := []*gitRef{
+		{Name: "refs/heads/master", Hash: "001"},
+		{Name: "refs/heads/release-1.2", Hash: "002"},
+		{Name: "refs/heads/release-1.10", Hash: "003"},
+		{Name: "refs/tags/1.9.1", Hash: "004"},
+		{Name: "refs/heads/v2.x", Hash: "005"},
+		{Name: "refs/tags/v3", Hash: "006"},
+		{Name: "refs/tags/1.11-unstable", Hash: "007"},
+	}
+	h := &Handler{
+		RefPatterns: []RefPattern{
+			MustRefPattern(RefTemplate("refs/heads/release-{major}.{minor}")),
+			MustRefPattern(RefTemplate("refs/tags/{major}.{minor}.{patch}")),
+			MustRefPattern(RefTemplate("refs/heads/v{major}.x")),
+		},
+	}
+	tests := []struct {
+		target, hash string
+	}{
+		{"v1", "003"},
+		{"v2", "005"},
+		{"v3", ""}, // refs/tags/v3 matches none of the patterns.
+		{"v0", "001"},
+	}
+	for _, tst := range tests {
+		chosen, ok := h.chooseRef(refs, ParseVersion(tst.target))
+		hash := bestHash(chosen)
+		if ok != (len(tst.hash) > 0) || hash != tst.hash {
+			t.Logf("%q\n", tst.target)
+			t.Fatalf("got %q (ok=%t) want %q\n", hash, ok, tst.hash)
+		}
+	}
+}
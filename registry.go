@@ -0,0 +1,375 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RegistryEntry is a single entry of a Registry, mapping an import path prefix
+// to a repository.
+type RegistryEntry struct {
+	// Prefix is the import path prefix, excluding the host, e.g. "pkg.v1" or
+	// "tools/lint". Import paths below the prefix are subpackages.
+	Prefix string `json:"prefix" yaml:"prefix" toml:"prefix"`
+
+	// Repo is the URL of the repository, e.g. "https://github.com/bob/pkg".
+	Repo string `json:"repo" yaml:"repo" toml:"repo"`
+
+	// VCS is the version control system of the repository. Only "git" is
+	// supported, which is also the default.
+	VCS string `json:"vcs,omitempty" yaml:"vcs,omitempty" toml:"vcs,omitempty"`
+
+	// Version is the major version of the repository to serve, e.g. "v1" (but
+	// not "v1.2"). If empty, the version is taken from the last element of the
+	// prefix if it is like "pkg.v1", or otherwise v0 is served.
+	Version string `json:"version,omitempty" yaml:"version,omitempty" toml:"version,omitempty"`
+
+	// Constraint, if non-empty, is a version constraint used to choose the
+	// branch or tag of the repository instead (see Repo.Constraint).
+	Constraint string `json:"constraint,omitempty" yaml:"constraint,omitempty" toml:"constraint,omitempty"`
+
+	// Subdir is the subdirectory of the repository holding the module, see
+	// Repo.Subdir.
+	Subdir string `json:"subdir,omitempty" yaml:"subdir,omitempty" toml:"subdir,omitempty"`
+
+	// Dir and File are the directory-view and file-view URL templates of the
+	// go-source meta tag, see TemplateConfig for the syntax. They may contain
+	// the {version}, {ref} and {kind} placeholders, as well as the go-source
+	// {/dir}, {file} and {line} placeholders.
+	Dir  string `json:"dir,omitempty" yaml:"dir,omitempty" toml:"dir,omitempty"`
+	File string `json:"file,omitempty" yaml:"file,omitempty" toml:"file,omitempty"`
+}
+
+// RegistryFile is the structure of a registry file, in any of the formats read
+// by Registry.Load (see RegisterRegistryDecoder). The yaml and toml struct tags
+// are used by e.g. the YAML and TOML decoders.
+type RegistryFile struct {
+	Entries []RegistryEntry `json:"entries" yaml:"entries" toml:"entries"`
+}
+
+// registryEntry is a validated RegistryEntry.
+type registryEntry struct {
+	RegistryEntry
+	url        *url.URL
+	version    Version
+	constraint *Constraint
+}
+
+// Registry is a Matcher using an explicit mapping of import path prefixes to
+// repositories. The import path of a request is matched to the entry with the
+// longest prefix, and any remaining path is the SubPath of the repository. For
+// example with the entries:
+//
+//  {"prefix": "pkg.v1", "repo": "https://github.com/bob/pkg"}
+//  {"prefix": "tools", "repo": "https://git.example.com/tools", "version": "v2"}
+//  {"prefix": "tools/lint", "repo": "https://git.example.com/lint"}
+//
+// It would match URLS like so:
+//
+//  example.com/pkg.v1 → github.com/bob/pkg (branch/tag v1, v1.N, or v1.N.M)
+//  example.com/pkg.v1/subpkg → github.com/bob/pkg (branch/tag v1, v1.N, or v1.N.M)
+//  example.com/tools/cmd → git.example.com/tools (branch/tag v2, v2.N, or v2.N.M)
+//  example.com/tools/lint → git.example.com/lint (master branch)
+//
+// The entries can be replaced at any time (e.g. using Load) while the
+// Registry is in use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries []*registryEntry // Sorted by descending prefix length.
+}
+
+// Match implements the Matcher interface.
+func (r *Registry) Match(u *url.URL) (repo *Repo, err error) {
+	rel := strings.TrimPrefix(u.Path, "/")
+	for _, elem := range strings.Split(rel, "/") {
+		if len(elem) == 0 {
+			return nil, ErrNotPackageURL
+		}
+	}
+
+	// Find the entry with the longest matching prefix.
+	r.mu.RLock()
+	var e *registryEntry
+	for _, entry := range r.entries {
+		if rel == entry.Prefix || strings.HasPrefix(rel, entry.Prefix+"/") {
+			e = entry
+			break
+		}
+	}
+	r.mu.RUnlock()
+	if e == nil {
+		return nil, ErrNotPackageURL
+	}
+
+	repoURL := *e.url
+	repo = &Repo{
+		Version:    e.version,
+		URL:        &repoURL,
+		SubPath:    strings.TrimPrefix(strings.TrimPrefix(rel, e.Prefix), "/"),
+		Constraint: e.constraint,
+		Subdir:     e.Subdir,
+	}
+
+	// Attach the go-source meta-tag, it is rebuilt by the Handler once the
+	// ref is chosen.
+	if len(e.Dir) > 0 || len(e.File) > 0 {
+		repo.GoSource = e.goSource(repo, u)
+		repo.GoSourceFunc = func(r *Repo) string {
+			return e.goSource(r, u)
+		}
+	}
+	return
+}
+
+// goSource returns the go-source meta tag for the given repository and go get
+// URL.
+func (e *registryEntry) goSource(r *Repo, u *url.URL) string {
+	vars := map[string]string{
+		"version": r.Version.String(),
+		"ref":     refName(r),
+		"kind":    "branch",
+	}
+	if strings.HasPrefix(r.Ref, "refs/tags/") {
+		vars["kind"] = "tag"
+	}
+	return goSource(r, u, expandTemplate(e.Dir, vars), expandTemplate(e.File, vars))
+}
+
+// Set validates the given entries and replaces the entries of the registry
+// with them. If any entry is invalid, an error is returned and the entries of
+// the registry are left unmodified.
+func (r *Registry) Set(entries []RegistryEntry) error {
+	valid, err := validateRegistry(entries)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.entries = valid
+	r.mu.Unlock()
+	return nil
+}
+
+// RegistryDecoder decodes the data of a registry file into the given value, a
+// *RegistryFile. For example yaml.Unmarshal of gopkg.in/yaml.v3, or
+// toml.Unmarshal of github.com/BurntSushi/toml.
+type RegistryDecoder func(data []byte, v interface{}) error
+
+var (
+	registryDecodersMu sync.RWMutex
+	registryDecoders   = map[string]RegistryDecoder{
+		".json": decodeRegistryJSON,
+	}
+)
+
+// RegisterRegistryDecoder registers the decoder of registry files with the
+// given file extension (e.g. ".yaml"), as used by Registry.Load. Only JSON
+// files (".json") are decoded by default, such that the package depends on no
+// YAML or TOML package. For example:
+//
+//  semver.RegisterRegistryDecoder(".yaml", yaml.Unmarshal)
+//  semver.RegisterRegistryDecoder(".yml", yaml.Unmarshal)
+//  semver.RegisterRegistryDecoder(".toml", toml.Unmarshal)
+//
+// Registering a decoder for an extension replaces any previous one.
+func RegisterRegistryDecoder(ext string, dec RegistryDecoder) {
+	registryDecodersMu.Lock()
+	registryDecoders[strings.ToLower(ext)] = dec
+	registryDecodersMu.Unlock()
+}
+
+// decodeRegistryJSON is the RegistryDecoder of JSON registry files. Unknown
+// fields are rejected, and syntax and type errors report their line and
+// column.
+func decodeRegistryJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	var offset int64 = -1
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	}
+	if offset >= 0 {
+		line, col := textPosition(data, offset)
+		return fmt.Errorf("%d:%d: %v", line, col, err)
+	}
+	return err
+}
+
+// Load reads the entries of the registry from the given registry file (see
+// RegistryFile), and replaces the entries of the registry with them. The file
+// is decoded by the decoder registered for its extension, see
+// RegisterRegistryDecoder. If the file cannot be read or is invalid, an error
+// is returned and the entries of the registry are left unmodified.
+func (r *Registry) Load(file string) error {
+	ext := strings.ToLower(filepath.Ext(file))
+	registryDecodersMu.RLock()
+	decode, ok := registryDecoders[ext]
+	registryDecodersMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s: no registry decoder for %q files", file, ext)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var f RegistryFile
+	if err := decode(data, &f); err != nil {
+		return fmt.Errorf("%s:%v", file, err)
+	}
+	if err := r.Set(f.Entries); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	return nil
+}
+
+// textPosition returns the line and column (both starting at one) of the given
+// byte offset in the data.
+func textPosition(data []byte, offset int64) (line, col int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = len(before) - bytes.LastIndexByte(before, '\n')
+	return
+}
+
+// NewRegistry returns a new Registry with the given entries, or an error if any
+// entry is invalid.
+func NewRegistry(entries []RegistryEntry) (*Registry, error) {
+	r := &Registry{}
+	if err := r.Set(entries); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// LoadRegistry returns a new Registry with the entries of the given registry
+// file (see Registry.Load), or an error if the file cannot be read or is
+// invalid.
+func LoadRegistry(file string) (*Registry, error) {
+	r := &Registry{}
+	if err := r.Load(file); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// validateRegistry validates the given entries and returns them sorted by
+// descending prefix length.
+func validateRegistry(entries []RegistryEntry) ([]*registryEntry, error) {
+	var (
+		valid = make([]*registryEntry, 0, len(entries))
+		seen  = make(map[string]int)
+	)
+	for i, entry := range entries {
+		e, err := validateRegistryEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("registry entry %d (prefix %q): %v", i, entry.Prefix, err)
+		}
+		if j, ok := seen[e.Prefix]; ok {
+			return nil, fmt.Errorf("registry entry %d (prefix %q): duplicate of entry %d", i, entry.Prefix, j)
+		}
+		seen[e.Prefix] = i
+		valid = append(valid, e)
+	}
+	sort.SliceStable(valid, func(i, j int) bool {
+		return len(valid[i].Prefix) > len(valid[j].Prefix)
+	})
+	return valid, nil
+}
+
+// validateRegistryEntry validates a single registry entry.
+func validateRegistryEntry(entry RegistryEntry) (*registryEntry, error) {
+	e := &registryEntry{RegistryEntry: entry}
+
+	// Validate the prefix.
+	e.Prefix = strings.TrimPrefix(e.Prefix, "/")
+	if len(e.Prefix) == 0 {
+		return nil, fmt.Errorf("empty prefix")
+	}
+	for _, elem := range strings.Split(e.Prefix, "/") {
+		if len(elem) == 0 {
+			return nil, fmt.Errorf("prefix has empty path element")
+		}
+	}
+
+	// Validate the repository URL.
+	if len(e.Repo) == 0 {
+		return nil, fmt.Errorf("missing repo URL")
+	}
+	u, err := url.Parse(e.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repo URL: %v", err)
+	}
+	if len(u.Host) == 0 {
+		return nil, fmt.Errorf("repo URL %q has no host", e.Repo)
+	}
+	// As with the GitHub matcher, the repository URL path has no leading
+	// slash.
+	u.Path = strings.TrimPrefix(u.Path, "/")
+	u.RawPath = strings.TrimPrefix(u.RawPath, "/")
+	e.url = u
+	switch e.VCS {
+	case "", "git":
+	default:
+		return nil, fmt.Errorf("unsupported VCS %q (only git is supported)", e.VCS)
+	}
+
+	// Validate the version and constraint.
+	switch {
+	case len(e.Version) > 0 && len(e.Constraint) > 0:
+		return nil, fmt.Errorf("both version and constraint given")
+	case len(e.Version) > 0:
+		v, err := ParseVersionStrict(e.Version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version: %v", err)
+		}
+		e.version = v
+	default:
+		e.version = ParseVersion("v0")
+		last := e.Prefix[strings.LastIndex(e.Prefix, "/")+1:]
+		if m := rePkgVersion.FindStringSubmatch(last); m != nil {
+			e.version = ParseVersion(m[2])
+		}
+	}
+	if e.version.Precision() > 1 {
+		// As with import paths, only the major version may be given.
+		return nil, fmt.Errorf("version %s may only contain major version", e.version)
+	}
+	if len(e.Constraint) > 0 {
+		c, err := ParseConstraint(e.Constraint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint: %v", err)
+		}
+		e.constraint = c
+	}
+	e.Subdir = strings.Trim(e.Subdir, "/")
+
+	// Validate the go-source templates.
+	known := map[string]bool{
+		"{version}": true, "{ref}": true, "{kind}": true,
+		"{/dir}": true, "{file}": true, "{line}": true,
+	}
+	if err := checkTemplate("dir", e.Dir, known); err != nil {
+		return nil, err
+	}
+	if err := checkTemplate("file", e.File, known); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var registryTests = []struct {
+	url, repo, version, subpath, subdir string
+	valid                               bool
+}{
+	{"pkg.v1", "https://github.com/bob/pkg", "v1", "", "", true},
+	{"/pkg.v1/sub/pkg", "https://github.com/bob/pkg", "v1", "sub/pkg", "", true},
+	{"tools", "https://git.example.com/tools.git", "v2", "", "", true},
+	{"tools/cmd", "https://git.example.com/tools.git", "v2", "cmd", "", true},
+	{"tools/lint", "https://git.example.com/tools.git", "v0", "", "lint", true},
+	{"tools/lint/rules", "https://git.example.com/tools.git", "v0", "rules", "lint", true},
+	{"tools/linter", "https://git.example.com/tools.git", "v2", "linter", "", true},
+	{"pkg.v2", "", "", "", "", false},
+	{"pkg.v1/", "", "", "", "", false},
+	{"tools//lint", "", "", "", "", false},
+}
+
+// Tests the registry URL matcher.
+func TestRegistry(t *testing.T) {
+	r, err := LoadRegistry("testdata/registry.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tst := range registryTests {
+		u, err := url.Parse(tst.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo, err := r.Match(u)
+		if !tst.valid {
+			if err != ErrNotPackageURL {
+				t.Log(u)
+				t.Fatal("want ErrNotPackageURL, got", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Log(u)
+			t.Fatal("Test is valid but matcher returned:", err)
+		}
+		if repo.URL.String() != tst.repo || repo.Version.String() != tst.version || repo.SubPath != tst.subpath || repo.Subdir != tst.subdir {
+			t.Log(u)
+			t.Logf("want %q %q %q %q\n", tst.repo, tst.version, tst.subpath, tst.subdir)
+			t.Logf("got %q %q %q %q\n", repo.URL, repo.Version, repo.SubPath, repo.Subdir)
+			t.Fatal("incorrect repo")
+		}
+	}
+
+	// Repository URL paths have no leading slash, as with the GitHub matcher.
+	if repo, _ := r.Match(&url.URL{Path: "/pkg.v1"}); repo.URL.Path != "bob/pkg" {
+		t.Fatalf("got repo URL path %q\n", repo.URL.Path)
+	}
+
+	// Options of the entries.
+	u, _ := url.Parse("https://example.com/tools/lint")
+	if repo, _ := r.Match(u); repo.Constraint == nil || repo.Constraint.String() != "^1.2" {
+		t.Fatal("missing constraint")
+	}
+	u, _ = url.Parse("https://example.com/pkg.v1/sub")
+	repo, _ := r.Match(u)
+	repo.Ref = "refs/tags/v1.2.0"
+	want := "example.com/pkg.v1 _ https://github.com/bob/pkg/tree/v1.2.0{/dir} https://github.com/bob/pkg/blob/v1.2.0{/dir}/{file}#L{line}"
+	if got := repo.GoSourceFunc(repo); got != want {
+		t.Log("want", want)
+		t.Log("got", got)
+		t.Fatal("incorrect go-source")
+	}
+}
+
+var registryInvalidTests = []struct {
+	entries []RegistryEntry
+	err     string
+}{
+	{[]RegistryEntry{{Repo: "https://a.com/b"}}, "registry entry 0 (prefix \"\"): empty prefix"},
+	{[]RegistryEntry{{Prefix: "a//b", Repo: "https://a.com/b"}}, "empty path element"},
+	{[]RegistryEntry{{Prefix: "a"}}, "missing repo URL"},
+	{[]RegistryEntry{{Prefix: "a", Repo: "a.com/b"}}, "has no host"},
+	{[]RegistryEntry{{Prefix: "a", Repo: "https://a.com/b", VCS: "hg"}}, "unsupported VCS \"hg\""},
+	{[]RegistryEntry{{Prefix: "a", Repo: "https://a.com/b", Version: "1.x"}}, "invalid version"},
+	{[]RegistryEntry{{Prefix: "a", Repo: "https://a.com/b", Version: "v1.2"}}, "version v1.2 may only contain major version"},
+	{[]RegistryEntry{{Prefix: "a.v1.2", Repo: "https://a.com/b"}}, "version v1.2 may only contain major version"},
+	{[]RegistryEntry{{Prefix: "a", Repo: "https://a.com/b", Constraint: ">>1"}}, "invalid constraint"},
+	{[]RegistryEntry{{Prefix: "a", Repo: "https://a.com/b", Version: "v1", Constraint: "^1"}}, "both version and constraint"},
+	{[]RegistryEntry{{Prefix: "a", Repo: "https://a.com/b", Dir: "{owner}{/dir}"}}, "unknown placeholder {owner}"},
+	{[]RegistryEntry{
+		{Prefix: "a", Repo: "https://a.com/b"},
+		{Prefix: "/a", Repo: "https://a.com/c"},
+	}, "registry entry 1 (prefix \"/a\"): duplicate of entry 0"},
+}
+
+// Tests that invalid registry entries are rejected.
+func TestRegistryInvalid(t *testing.T) {
+	for _, tst := range registryInvalidTests {
+		_, err := NewRegistry(tst.entries)
+		if err == nil || !strings.Contains(err.Error(), tst.err) {
+			t.Logf("%+v\n", tst.entries)
+			t.Fatalf("want error containing %q, got %v\n", tst.err, err)
+		}
+	}
+}
+
+// Tests that reloading a registry replaces all of its entries, or none of them.
+func TestRegistryReload(t *testing.T) {
+	r, err := NewRegistry([]RegistryEntry{{Prefix: "old", Repo: "https://a.com/old"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://example.com/old")
+
+	// A file with a syntax error.
+	err = r.Load("testdata/registry-syntax.json")
+	if err == nil || !strings.HasPrefix(err.Error(), "testdata/registry-syntax.json:3:") {
+		t.Fatal("want syntax error with position, got", err)
+	}
+	if _, err := r.Match(u); err != nil {
+		t.Fatal("entries changed by failed reload:", err)
+	}
+
+	// A valid file.
+	if err := r.Load("testdata/registry.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Match(u); err != ErrNotPackageURL {
+		t.Fatal("want ErrNotPackageURL after reload, got", err)
+	}
+}
+
+// decodeRegistryText decodes a toy registry file format with one "prefix repo"
+// entry per line.
+func decodeRegistryText(data []byte, v interface{}) error {
+	f := v.(*RegistryFile)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid line %q", line)
+		}
+		f.Entries = append(f.Entries, RegistryEntry{Prefix: fields[0], Repo: fields[1]})
+	}
+	return nil
+}
+
+// Tests that registry files are decoded by the decoder registered for their
+// extension.
+func TestRegistryDecoder(t *testing.T) {
+	r := &Registry{}
+	err := r.Load("testdata/registry.txt")
+	if err == nil || !strings.Contains(err.Error(), `no registry decoder for ".txt" files`) {
+		t.Fatal("want unsupported format error, got", err)
+	}
+
+	RegisterRegistryDecoder(".TXT", decodeRegistryText)
+	if err := r.Load("testdata/registry.txt"); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://example.com/tools/lint")
+	repo, err := r.Match(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.URL.String() != "https://git.example.com/tools.git" || repo.SubPath != "lint" {
+		t.Logf("%+v\n", repo)
+		t.Fatal("incorrect repo")
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// VersionScheme defines how versions are written in branch and tag names, how
+// they are ordered, and which major line (i.e. import path version, like the
+// 2 in "example.com/pkg.v2") they belong to.
+type VersionScheme interface {
+	// Parse parses the given branch or tag name into a version. If the name
+	// is not a valid version in this scheme, an error is returned.
+	Parse(s string) (Version, error)
+
+	// Compare compares the two versions, it returns -1 if a is less than b, +1
+	// if b is less than a, and zero otherwise.
+	Compare(a, b Version) int
+
+	// Format returns the string form of the given version, such that it can
+	// be parsed again by Parse.
+	Format(v Version) string
+
+	// Line returns the major line of the given version, that is the version
+	// number in import paths which select the version.
+	Line(v Version) int
+}
+
+// SemVer is the default VersionScheme used by a Handler: it parses versions
+// like "v1", "v1.2" and "v1.2.3-rc.1" using ParseVersion, formats them using
+// Version.String, and the major line of a version is its major version number.
+type SemVer struct {
+	// The ordering used to compare versions.
+	Ordering Ordering
+}
+
+// Parse implements the VersionScheme interface. Parsing is lenient, as with
+// ParseVersion, but an error is returned instead of InvalidVersion.
+func (s SemVer) Parse(vs string) (Version, error) {
+	return parseVersion(vs, false)
+}
+
+// Compare implements the VersionScheme interface.
+func (s SemVer) Compare(a, b Version) int {
+	return s.Ordering.Compare(a, b)
+}
+
+// Format implements the VersionScheme interface.
+func (s SemVer) Format(v Version) string {
+	return v.String()
+}
+
+// Line implements the VersionScheme interface.
+func (s SemVer) Line(v Version) int {
+	return v.Major
+}
+
+// CalVer is a VersionScheme for calendar versioning, where the major version
+// number is a two or four digit year, for example:
+//
+//  "2026.10.1" -> Version{Major=2026, Minor=10, Patch=1}
+//  "v24.04"    -> Version{Major=24, Minor=4, Patch=-1}
+//  "2026.10-rc.1"
+//
+// The "v" prefix is optional, and the minor and patch version numbers may have
+// leading zeros (e.g. a zero-padded month). The major line of a version is its
+// year, such that "example.com/pkg.v2026" selects the latest 2026 release.
+// Versions are compared using SpecOrdering.
+type CalVer struct {
+	// Prefix is written before formatted versions, e.g. "v".
+	Prefix string
+
+	// If true, the minor version number is zero-padded to two digits when
+	// formatted (e.g. "v24.04" instead of "v24.4").
+	ZeroPad bool
+}
+
+// Parse implements the VersionScheme interface.
+func (c CalVer) Parse(vs string) (Version, error) {
+	fail := func(pos int, msg string) (Version, error) {
+		return InvalidVersion, &ParseError{Input: vs, Pos: pos, Msg: msg}
+	}
+	pos := 0
+	if len(vs) > 0 && vs[0] == 'v' {
+		pos++
+	}
+
+	v := InvalidVersion
+	for n := 0; n < 3; n++ {
+		start := pos
+		for pos < len(vs) && '0' <= vs[pos] && vs[pos] <= '9' {
+			pos++
+		}
+		num := vs[start:pos]
+		if len(num) == 0 {
+			return fail(pos, "expected version number")
+		}
+		if n == 0 && len(num) != 2 && len(num) != 4 {
+			return fail(start, "year must have two or four digits")
+		}
+		i, err := strconv.Atoi(num)
+		if err != nil {
+			return fail(start, "version number out of range")
+		}
+		switch n {
+		case 0:
+			v.Major = i
+		case 1:
+			v.Minor = i
+		case 2:
+			v.Patch = i
+		}
+		if n == 2 || pos == len(vs) || vs[pos] != '.' {
+			break
+		}
+		pos++ // Skip the dot.
+	}
+	if i, msg := parseSuffix(vs, pos, &v); i != -1 {
+		return fail(i, msg)
+	}
+	return v, nil
+}
+
+// Compare implements the VersionScheme interface.
+func (c CalVer) Compare(a, b Version) int {
+	return SpecOrdering.Compare(a, b)
+}
+
+// Format implements the VersionScheme interface.
+func (c CalVer) Format(v Version) string {
+	if v.Precision() == 0 {
+		return v.String()
+	}
+	s := c.Prefix + strconv.Itoa(v.Major)
+	if v.Minor >= 0 {
+		if c.ZeroPad {
+			s += fmt.Sprintf(".%02d", v.Minor)
+		} else {
+			s += "." + strconv.Itoa(v.Minor)
+		}
+	}
+	if v.Minor >= 0 && v.Patch >= 0 {
+		s += "." + strconv.Itoa(v.Patch)
+	}
+	if pre := v.preRelease(); len(pre) > 0 {
+		s += "-" + pre
+	}
+	if len(v.Metadata) > 0 {
+		s += "+" + v.Metadata
+	}
+	return s
+}
+
+// Line implements the VersionScheme interface.
+func (c CalVer) Line(v Version) int {
+	return v.Major
+}
@@ -0,0 +1,153 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var calVerParseTests = []struct {
+	s   string
+	exp Version
+	pos int // Position of the error, or -1 if valid.
+}{
+	// Valid version strings.
+	{s: "2026.10.1", exp: Version{Major: 2026, Minor: 10, Patch: 1}, pos: -1},
+	{s: "v24.04", exp: Version{Major: 24, Minor: 4, Patch: -1}, pos: -1},
+	{s: "v2026", exp: Version{Major: 2026, Minor: -1, Patch: -1}, pos: -1},
+	{s: "2026.10-rc.1", exp: Version{Major: 2026, Minor: 10, Patch: -1, PreRelease: "rc.1"}, pos: -1},
+	{s: "2026.01.02+build.5", exp: Version{Major: 2026, Minor: 1, Patch: 2, Metadata: "build.5"}, pos: -1},
+
+	// Invalid version strings.
+	{s: "", pos: 0},
+	{s: "v1.2.3", pos: 1},
+	{s: "202.1", pos: 0},
+	{s: "2026.", pos: 5},
+	{s: "2026.10.1.1", pos: 9},
+	{s: "2026x", pos: 4},
+	{s: "master", pos: 0},
+}
+
+func TestCalVerParse(t *testing.T) {
+	for _, tst := range calVerParseTests {
+		got, err := CalVer{}.Parse(tst.s)
+		if tst.pos == -1 {
+			if err != nil {
+				t.Logf("%q\n", tst.s)
+				t.Fatal(err)
+			}
+			if got != tst.exp {
+				t.Logf("%q\n", tst.s)
+				t.Fatalf("got %#v want %#v\n", got, tst.exp)
+			}
+			continue
+		}
+		pErr, ok := err.(*ParseError)
+		if !ok {
+			t.Logf("%q\n", tst.s)
+			t.Fatalf("want *ParseError, got %#v\n", err)
+		}
+		if pErr.Pos != tst.pos {
+			t.Logf("%q\n", tst.s)
+			t.Fatalf("got error %q at %d, want position %d\n", pErr, pErr.Pos, tst.pos)
+		}
+	}
+}
+
+func TestCalVerFormat(t *testing.T) {
+	tests := []struct {
+		scheme CalVer
+		s, exp string
+	}{
+		{CalVer{}, "2026.10.1", "2026.10.1"},
+		{CalVer{}, "v24.04", "24.4"},
+		{CalVer{Prefix: "v", ZeroPad: true}, "v24.04", "v24.04"},
+		{CalVer{Prefix: "v", ZeroPad: true}, "2026.10-rc.1+b", "v2026.10-rc.1+b"},
+		{CalVer{}, "2026", "2026"},
+	}
+	for _, tst := range tests {
+		v, err := tst.scheme.Parse(tst.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tst.scheme.Format(v); got != tst.exp {
+			t.Fatalf("Format(Parse(%q)) got %q want %q\n", tst.s, got, tst.exp)
+		}
+	}
+}
+
+func TestSemVerScheme(t *testing.T) {
+	s := SemVer{}
+	v, err := s.Parse("v1.2.3-rc.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Format(v) != "v1.2.3-rc.1" || s.Line(v) != 1 {
+		t.Fatalf("got %q line %d\n", s.Format(v), s.Line(v))
+	}
+	if _, err := s.Parse("master"); err == nil {
+		t.Fatal("want error parsing master")
+	}
+	a, b := ParseVersion("v3-unstable"), ParseVersion("v2")
+	if s.Compare(a, b) != -1 || (SemVer{Ordering: SpecOrdering}).Compare(a, b) != 1 {
+		t.Fatal("SemVer.Compare does not follow Ordering")
+	}
+}
+
+func TestChooseRefCalVer(t *testing.T) {
+	refs := []*gitRef{
+		{Name: "refs/heads/master", Hash: "001"},
+		{Name: "refs/tags/2025.12.3", Hash: "002"},
+		{Name: "refs/tags/2026.9.3", Hash: "003"},
+		{Name: "refs/tags/2026.10.1", Hash: "004"},
+		{Name: "refs/tags/2026.10.2-rc.1", Hash: "005"},
+		{Name: "refs/tags/v1.2.3", Hash: "006"},
+	}
+
+	// Route an import path through the GitHub matcher.
+	repo, err := GitHub("bob").Match(&url.URL{Path: "/pkg.v2026"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{Scheme: CalVer{}}
+	chosen, ok := h.chooseRef(refs, repo.Version)
+	if hash := bestHash(chosen); !ok || hash != "004" {
+		t.Fatalf("got %q (ok=%t) want %q\n", hash, ok, "004")
+	}
+	if _, ok := h.chooseRef(refs, ParseVersion("v1")); ok {
+		t.Fatal("want ok=false for semver line with CalVer scheme")
+	}
+	chosen, ok = h.chooseRefConstraint(refs, MustParseConstraint(">=2025.12 <2026.10"))
+	if hash := bestHash(chosen); !ok || hash != "003" {
+		t.Fatalf("got %q (ok=%t) want %q\n", hash, ok, "003")
+	}
+}
+
+// Tests that the go-source meta tag served for a CalVer import path links to
+// the chosen tag, rather than to the import path version.
+func TestCalVerHandle(t *testing.T) {
+	h := &Handler{
+		Host:    "example.com",
+		Matcher: GitHub("bob"),
+		Scheme:  CalVer{},
+		Client: &http.Client{Transport: fileTransport{
+			"https://github.com/bob/pkg.git/info/refs?service=git-upload-pack": "testdata/github-bob-calver",
+		}},
+	}
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://example.com/pkg.v2026?go-get=1", nil)
+	if s, err := h.Handle(w, r); s != Handled || err != nil {
+		t.Fatalf("got status %v err %v\n", s, err)
+	}
+	want := `<meta name="go-source" content="example.com/pkg.v2026 _ https://github.com/bob/pkg/tree/2026.10.1{/dir} https://github.com/bob/pkg/blob/2026.10.1{/dir}/{file}#L{line}">`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Log(w.Body.String())
+		t.Fatal("missing", want)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/url"
+	"path"
+)
+
+// sourcehut is a Matcher that represents a single SourceHut user.
+type sourcehut struct {
+	host, user string
+}
+
+// sourcehutGoSource returns a go-source meta-tag for the given repository and
+// go get URL.
+func sourcehutGoSource(r *Repo, u *url.URL) string {
+	srhtURL := webURL(r)
+	ref := sourceRef(r)
+
+	// Build a directory-view URL like so:
+	//
+	//  https://git.sr.ht/~user/pkg/tree/v2/item{/dir}
+	//
+	dirURL := srhtURL
+	dirURL.Path = path.Join(dirURL.Path, "tree", ref, "item")
+	dir := dirURL.String() + "{/dir}"
+
+	// Build a file-view URL like so:
+	//
+	//  https://git.sr.ht/~user/pkg/tree/v2/item{/dir}/{file}#L{line}
+	//
+	file := dirURL.String() + "{/dir}/{file}#L{line}"
+
+	return goSource(r, u, dir, file)
+}
+
+// Match implements the Matcher interface. SourceHut repository URLs have no
+// .git suffix.
+func (s sourcehut) Match(u *url.URL) (repo *Repo, err error) {
+	return matchDashed(u, s.host, "~"+s.user, sourcehutGoSource)
+}
+
+// SourceHut returns a URL Matcher that operates on the repositories of a single
+// SourceHut user (given without the tilde). Import paths are mapped to
+// repositories as with GitHub, for instance if the service was running at
+// example.com and the user string was "bob", it would match URLS in the
+// pattern of:
+//
+//  example.com/pkg.v3 → git.sr.ht/~bob/pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/folder/pkg.v3 → git.sr.ht/~bob/folder-pkg (branch/tag v3, v3.N, or v3.N.M)
+//  example.com/pkg.v3/folder/subpkg → git.sr.ht/~bob/pkg (branch/tag v3, v3.N, or v3.N.M)
+//
+func SourceHut(user string) Matcher {
+	return sourcehut{"git.sr.ht", user}
+}
@@ -0,0 +1,114 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var sourceHutTests = []struct {
+	url, path, subpath string
+	valid              bool
+}{
+	{"pkg.v1", "~bob/pkg", "", true},
+	{"folder/pkg.v1/subpkg", "~bob/folder-pkg", "subpkg", true},
+	{"pkg.v1/", "", "", false},
+	{"pkg", "", "", false},
+}
+
+// Tests the SourceHut URL matcher.
+func TestSourceHut(t *testing.T) {
+	for _, tst := range sourceHutTests {
+		u, err := url.Parse(tst.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repo, err := SourceHut("bob").Match(u)
+		if tst.valid && err != nil {
+			t.Log(u)
+			t.Fatal("Test is valid but matcher returned:", err)
+		} else if !tst.valid && err == nil {
+			t.Log(u)
+			t.Fatal("Test is invalid but matcher returned nil error!")
+		}
+		if !tst.valid {
+			continue
+		}
+		if repo.URL.Host != "git.sr.ht" || repo.URL.Path != tst.path || repo.SubPath != tst.subpath {
+			t.Log(u)
+			t.Logf("want %q %q\n", tst.path, tst.subpath)
+			t.Logf("got %q %q %q\n", repo.URL.Host, repo.URL.Path, repo.SubPath)
+			t.Fatal("incorrect repo")
+		}
+	}
+}
+
+// Tests a Handler using the SourceHut URL matcher, against a recorded info/refs
+// reply.
+func TestSourceHutHandle(t *testing.T) {
+	h := &Handler{
+		Host:    "example.com",
+		Matcher: SourceHut("bob"),
+		Client: &http.Client{Transport: fileTransport{
+			"https://git.sr.ht/~bob/pkg/info/refs?service=git-upload-pack": "testdata/sourcehut-bob-pkg",
+		}},
+	}
+
+	// The go-import and go-source meta tags.
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "https://example.com/pkg.v1/subpkg?go-get=1", nil)
+	if s, err := h.Handle(w, r); s != Handled || err != nil {
+		t.Fatalf("got status %v err %v\n", s, err)
+	}
+	for _, want := range []string{
+		`<meta name="go-import" content="example.com/pkg.v1 git https://example.com/pkg.v1">`,
+		`<meta name="go-source" content="example.com/pkg.v1 _ https://git.sr.ht/~bob/pkg/tree/v1.1.0/item{/dir} https://git.sr.ht/~bob/pkg/tree/v1.1.0/item{/dir}/{file}#L{line}">`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Log(w.Body.String())
+			t.Fatal("missing", want)
+		}
+	}
+
+	// The modified info/refs reply.
+	for _, tst := range []struct {
+		path, master string
+	}{
+		{"/pkg.v1", "d4f6b8a0c2e4f6a8b0c2e4f6a8b0c2e4f6a8b0c2"},
+		{"/pkg.v2", "8a4e2c0b6d1f3e5a7c9b0d2f4e6a8c1b3d5f7e9a"},
+		{"/pkg.v0", "2f1c8e7a9b0d4c6e8f1a3b5c7d9e0f2a4b6c8d0e"},
+	} {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "https://example.com"+tst.path+"/info/refs?service=git-upload-pack", nil)
+		if s, err := h.Handle(w, r); s != Handled || err != nil {
+			t.Fatalf("got status %v err %v\n", s, err)
+		}
+		refs, err := gitParseRefs(w.Body.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if refs.mainID != tst.master {
+			t.Log(tst.path)
+			t.Fatalf("got HEAD %q want %q\n", refs.mainID, tst.master)
+		}
+		for _, ref := range refs.records {
+			if ref.Name == "refs/heads/master" && ref.Hash != tst.master {
+				t.Log(tst.path)
+				t.Fatalf("got master %q want %q\n", ref.Hash, tst.master)
+			}
+		}
+	}
+
+	// A version which does not exist.
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest("GET", "https://example.com/pkg.v3/info/refs?service=git-upload-pack", nil)
+	if s, err := h.Handle(w, r); s != Handled || err != nil || w.Code != http.StatusNotFound {
+		t.Fatalf("got status %v err %v code %d\n", s, err, w.Code)
+	}
+}
@@ -0,0 +1,335 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// TemplateConfig configures a Matcher created by TemplateMatcher.
+type TemplateConfig struct {
+	// Import is the template of the import path (excluding the host), for
+	// example "{path}/{pkg}.v{major}". It may contain the placeholders:
+	//
+	//  {path}   zero or more path elements (if followed by a slash), or one or more
+	//  {pkg}    the package name, e.g. "pkg"
+	//  {major}  the major version, e.g. "3" or "3-unstable" (required)
+	//  {name}   any other name captures a single path element, e.g. {owner}
+	//
+	// Captured path elements may only contain ASCII letters, digits, dots,
+	// dashes and underscores (and may not start with a dot).
+	// The names set by the matcher itself (name, version, ref and kind, see
+	// TemplateMatcher) and subpath cannot be captured. All other text is
+	// matched literally. Any path following the import path template becomes
+	// the SubPath of the repository.
+	Import string
+
+	// Repo is the template of the repository URL, for example:
+	//
+	//  "https://git.example.com/{owner}/{pkg}.git"
+	//
+	Repo string
+
+	// Dir and File are the templates of the directory-view and file-view URLs
+	// of the go-source meta tag, for example:
+	//
+	//  "https://git.example.com/{owner}/{pkg}/tree/{ref}{/dir}"
+	//  "https://git.example.com/{owner}/{pkg}/blob/{ref}{/dir}/{file}#L{line}"
+	//
+	// If both are empty, no go-source meta tag is served.
+	Dir, File string
+
+	// Vars are additional placeholder values to use in the Repo, Dir and File
+	// templates. As with captures, the names set by the matcher are reserved.
+	Vars map[string]string
+}
+
+// templateMatcher is a Matcher created by TemplateMatcher.
+type templateMatcher struct {
+	TemplateConfig
+	re *regexp.Regexp
+}
+
+// importPlaceholders maps the fixed placeholders of import path templates to
+// their regular expressions.
+var importPlaceholders = map[string]string{
+	"{pkg}":   `(?P<pkg>[a-zA-Z0-9-]+)`,
+	"{major}": `(?P<major>[0-9]+(?:\.[0-9]+){0,2}(?:-unstable)?)`,
+}
+
+// templateElem is the regular expression of the path elements captured by the
+// {path} and {name} placeholders of import path templates. Like {pkg}, it only
+// allows characters which are expanded verbatim into the URL templates, such
+// that a request cannot alter e.g. the host or query of the repository URL.
+const templateElem = `[a-zA-Z0-9_-][a-zA-Z0-9._-]*`
+
+// reTemplateName matches the names of placeholders in templates.
+var reTemplateName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedTemplateNames are the placeholder names which are set by the matcher
+// itself, and thus cannot be captured or given as Vars.
+var reservedTemplateNames = map[string]bool{
+	"name":    true,
+	"version": true,
+	"ref":     true,
+	"kind":    true,
+	"subpath": true,
+}
+
+// templateVars returns the placeholder values for the given repository, as used
+// in the Repo, Dir and File templates.
+func (t *templateMatcher) templateVars(r *Repo, captures map[string]string) map[string]string {
+	vars := make(map[string]string, len(t.Vars)+len(captures)+4)
+	for k, v := range t.Vars {
+		vars[k] = v
+	}
+	for k, v := range captures {
+		vars[k] = v
+	}
+	var elems []string
+	if len(captures["path"]) > 0 {
+		elems = strings.Split(captures["path"], "/")
+	}
+	vars["name"] = strings.Join(append(elems, captures["pkg"]), "-")
+	vars["version"] = r.Version.String()
+	vars["ref"] = refName(r)
+	vars["kind"] = "branch"
+	if strings.HasPrefix(r.Ref, "refs/tags/") {
+		vars["kind"] = "tag"
+	}
+	return vars
+}
+
+// goSource returns the go-source meta tag for the given repository and go get
+// URL.
+func (t *templateMatcher) goSource(r *Repo, u *url.URL, captures map[string]string) string {
+	vars := t.templateVars(r, captures)
+	return goSource(r, u, expandTemplate(t.Dir, vars), expandTemplate(t.File, vars))
+}
+
+// Match implements the Matcher interface.
+func (t *templateMatcher) Match(u *url.URL) (repo *Repo, err error) {
+	// As with the GitHub matcher, paths with empty elements ("/a//b/c" or a
+	// trailing slash) or more than one version element are not valid.
+	rel := strings.TrimPrefix(u.Path, "/")
+	versions := 0
+	for _, elem := range strings.Split(rel, "/") {
+		if len(elem) == 0 {
+			return nil, ErrNotPackageURL
+		}
+		if rePkgVersion.MatchString(elem) {
+			versions++
+		}
+	}
+	if versions > 1 {
+		return nil, ErrNotPackageURL
+	}
+
+	m := t.re.FindStringSubmatch(rel)
+	if m == nil {
+		return nil, ErrNotPackageURL
+	}
+	captures := make(map[string]string)
+	for i, name := range t.re.SubexpNames() {
+		if len(name) > 0 {
+			captures[name] = m[i]
+		}
+	}
+	subPath := captures["subpath"]
+	delete(captures, "subpath")
+
+	// Parse the version string.
+	v := ParseVersion("v" + captures["major"])
+	if v.Precision() > 1 {
+		return nil, &HTTPError{
+			error:  fmt.Errorf("Import path may only contain major version."),
+			Status: http.StatusNotFound,
+		}
+	}
+	repo = &Repo{
+		Version: v,
+		SubPath: subPath,
+	}
+
+	// Build the repository URL. As with the GitHub matcher, its path has no
+	// leading slash.
+	repo.URL, err = url.Parse(expandTemplate(t.Repo, t.templateVars(repo, captures)))
+	if err != nil {
+		return nil, err
+	}
+	repo.URL.Path = strings.TrimPrefix(repo.URL.Path, "/")
+
+	// Attach the go-source meta-tag, it is rebuilt by the Handler once the
+	// ref is chosen.
+	if len(t.Dir) > 0 || len(t.File) > 0 {
+		repo.GoSource = t.goSource(repo, u, captures)
+		repo.GoSourceFunc = func(r *Repo) string {
+			return t.goSource(r, u, captures)
+		}
+	}
+	return
+}
+
+// expandTemplate replaces the placeholders in the template with their values,
+// placeholders without a value are left unmodified.
+func expandTemplate(tmpl string, vars map[string]string) string {
+	var s string
+	for rest := tmpl; len(rest) > 0; {
+		start := strings.IndexByte(rest, '{')
+		end := strings.IndexByte(rest, '}')
+		if start == -1 || end < start {
+			s += rest
+			break
+		}
+		v, ok := vars[rest[start+1:end]]
+		if !ok {
+			v = rest[start : end+1]
+		}
+		s += rest[:start] + v
+		rest = rest[end+1:]
+	}
+	return s
+}
+
+// checkTemplate checks that the template only uses the given placeholders.
+func checkTemplate(field, tmpl string, known map[string]bool) error {
+	for rest := tmpl; len(rest) > 0; {
+		start := strings.IndexByte(rest, '{')
+		if start == -1 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end == -1 {
+			return fmt.Errorf("%s template %q has unterminated placeholder", field, tmpl)
+		}
+		end += start + 1
+		if placeholder := rest[start:end]; !known[placeholder] {
+			return fmt.Errorf("%s template %q has unknown placeholder %s", field, tmpl, placeholder)
+		}
+		rest = rest[end:]
+	}
+	return nil
+}
+
+// TemplateMatcher returns a URL Matcher that matches import paths against the
+// import path template of the given configuration, and builds repositories
+// using its URL templates. The Repo, Dir and File templates may contain the
+// captures of the import path template (e.g. {path}, {pkg}, {major} and
+// {owner}), the configured Vars, and:
+//
+//  {name}     the path elements and package name joined with dashes, like the
+//             repository names of the GitHub matcher, e.g. "folder-pkg"
+//  {version}  the version of the import path, e.g. "v3" or "v3-unstable"
+//  {ref}      the branch or tag name chosen for the version, e.g. "v3.1.0"
+//  {kind}     the kind of the chosen ref, "branch" or "tag"
+//
+// The Dir and File templates may also contain the go-source placeholders
+// {/dir}, {file} and {line}. For example, the following is equivalent to the
+// GitHub matcher for the user "bob":
+//
+//  semver.TemplateMatcher(semver.TemplateConfig{
+//      Import: "{path}/{pkg}.v{major}",
+//      Repo:   "https://github.com/bob/{name}.git",
+//      Dir:    "https://github.com/bob/{name}/tree/{ref}{/dir}",
+//      File:   "https://github.com/bob/{name}/blob/{ref}{/dir}/{file}#L{line}",
+//  })
+//
+// The returned Matcher behaves like the GitHub one: URLs not matching the
+// template result in ErrNotPackageURL, and import paths with a version that is
+// not just a major version result in a *HTTPError with a 404 status.
+func TemplateMatcher(c TemplateConfig) (Matcher, error) {
+	var (
+		expr string
+		seen = make(map[string]bool)
+	)
+	for rest := c.Import; len(rest) > 0; {
+		start := strings.IndexByte(rest, '{')
+		if start == -1 {
+			expr += regexp.QuoteMeta(rest)
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end == -1 {
+			return nil, fmt.Errorf("import template %q has unterminated placeholder", c.Import)
+		}
+		end += start + 1
+		placeholder := rest[start:end]
+		name := placeholder[1 : len(placeholder)-1]
+		if seen[placeholder] {
+			return nil, fmt.Errorf("import template %q has duplicate placeholder %s", c.Import, placeholder)
+		}
+		seen[placeholder] = true
+		expr += regexp.QuoteMeta(rest[:start])
+		rest = rest[end:]
+
+		re, ok := importPlaceholders[placeholder]
+		switch {
+		case ok:
+		case name == "path" && strings.HasPrefix(rest, "/"):
+			re = `(?:(?P<path>` + templateElem + `(?:/` + templateElem + `)*)/)?`
+			rest = rest[1:]
+		case name == "path":
+			re = `(?P<path>` + templateElem + `(?:/` + templateElem + `)*)`
+		case reservedTemplateNames[name] || !reTemplateName.MatchString(name):
+			return nil, fmt.Errorf("import template %q has invalid placeholder %s", c.Import, placeholder)
+		default:
+			re = `(?P<` + name + `>` + templateElem + `)`
+		}
+		expr += re
+	}
+	if !seen["{major}"] {
+		return nil, fmt.Errorf("import template %q has no {major} placeholder", c.Import)
+	}
+	if len(c.Repo) == 0 {
+		return nil, fmt.Errorf("template matcher has no repo template")
+	}
+
+	// Check the URL templates.
+	known := map[string]bool{"{name}": true, "{version}": true, "{ref}": true, "{kind}": true}
+	for placeholder := range seen {
+		known[placeholder] = true
+	}
+	for k := range c.Vars {
+		if reservedTemplateNames[k] {
+			return nil, fmt.Errorf("template matcher var %q is reserved", k)
+		}
+		known["{"+k+"}"] = true
+	}
+	if err := checkTemplate("repo", c.Repo, known); err != nil {
+		return nil, err
+	}
+	for _, placeholder := range []string{"{/dir}", "{file}", "{line}"} {
+		known[placeholder] = true
+	}
+	if err := checkTemplate("dir", c.Dir, known); err != nil {
+		return nil, err
+	}
+	if err := checkTemplate("file", c.File, known); err != nil {
+		return nil, err
+	}
+
+	re, err := regexp.Compile(`^` + expr + `(?:/(?P<subpath>.+))?$`)
+	if err != nil {
+		return nil, err
+	}
+	return &templateMatcher{TemplateConfig: c, re: re}, nil
+}
+
+// MustMatcher is a helper that wraps a call to a function returning (Matcher,
+// error) and panics if the error is non-nil. It is intended for use in variable
+// initializations such as:
+//
+//  var m = semver.MustMatcher(semver.TemplateMatcher(config))
+//
+func MustMatcher(m Matcher, err error) Matcher {
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
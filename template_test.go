@@ -0,0 +1,141 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"net/url"
+	"testing"
+)
+
+// gitHubTemplate is a template matcher configuration equivalent to the GitHub
+// matcher for the user "bob".
+var gitHubTemplate = TemplateConfig{
+	Import: "{path}/{pkg}.v{major}",
+	Repo:   "https://github.com/{owner}/{name}.git",
+	Dir:    "https://github.com/{owner}/{name}/tree/{ref}{/dir}",
+	File:   "https://github.com/{owner}/{name}/blob/{ref}{/dir}/{file}#L{line}",
+	Vars:   map[string]string{"owner": "bob"},
+}
+
+// Tests that the template matcher behaves exactly like the GitHub matcher.
+func TestTemplateMatcherGitHub(t *testing.T) {
+	matcher := MustMatcher(TemplateMatcher(gitHubTemplate))
+	for _, tst := range gitHubTests {
+		u, err := url.Parse("https://example.com/" + tst.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, wantErr := GitHub("bob").Match(u)
+		repo, err := matcher.Match(u)
+		if _, ok := wantErr.(*HTTPError); ok {
+			if _, ok := err.(*HTTPError); !ok {
+				t.Log(u)
+				t.Fatal("want *HTTPError, got", err)
+			}
+			continue
+		}
+		if err != wantErr {
+			t.Log(u)
+			t.Fatalf("want error %v, got %v\n", wantErr, err)
+		}
+		if err != nil {
+			continue
+		}
+		if repo.URL.Host != want.URL.Host || repo.URL.Path != want.URL.Path {
+			t.Log(u)
+			t.Log("want", want.URL)
+			t.Log("got", repo.URL)
+			t.Fatal("incorrect repo URL")
+		}
+		if repo.Version != want.Version || repo.SubPath != want.SubPath || repo.GoSource != want.GoSource {
+			t.Log(u)
+			t.Logf("want %v %q %q\n", want.Version, want.SubPath, want.GoSource)
+			t.Logf("got %v %q %q\n", repo.Version, repo.SubPath, repo.GoSource)
+			t.Fatal("incorrect repo")
+		}
+	}
+}
+
+// Tests the template matcher with a custom host and captured placeholders.
+func TestTemplateMatcher(t *testing.T) {
+	matcher := MustMatcher(TemplateMatcher(TemplateConfig{
+		Import: "{owner}/{pkg}.v{major}",
+		Repo:   "https://git.corp/{owner}/{pkg}.git",
+		Dir:    "https://git.corp/{owner}/{pkg}/src/{kind}/{ref}{/dir}",
+	}))
+	u, err := url.Parse("https://example.com/bob/pkg.v2-unstable/subpkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := matcher.Match(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.URL.String() != "https://git.corp/bob/pkg.git" || repo.SubPath != "subpkg" || repo.Version != ParseVersion("v2-unstable") {
+		t.Log(repo.URL, repo.SubPath, repo.Version)
+		t.Fatal("incorrect repo")
+	}
+	repo.Ref = "refs/tags/v2.1.0-unstable"
+	want := "example.com/bob/pkg.v2-unstable _ https://git.corp/bob/pkg/src/tag/v2.1.0-unstable{/dir} "
+	if got := repo.GoSourceFunc(repo); got != want {
+		t.Log("want", want)
+		t.Log("got", got)
+		t.Fatal("incorrect go-source")
+	}
+
+	// Captures cannot change the host, path or query of the repository URL.
+	for _, tst := range []struct {
+		imp, repo, path string
+	}{
+		{"{owner}/{pkg}.v{major}", "https://{owner}.git.corp/{pkg}.git", "/evil.com%23/pkg.v1"},
+		{"{owner}/{pkg}.v{major}", "https://{owner}.git.corp/{pkg}.git", "/evil.com%2F/pkg.v1"},
+		{"{owner}/{pkg}.v{major}", "https://git.corp/{owner}/{pkg}.git", "/a%3Fb/pkg.v1"},
+		{"{owner}/{pkg}.v{major}", "https://git.corp/{owner}/{pkg}.git", "/../pkg.v1"},
+		{"{path}/{pkg}.v{major}", "https://git.corp/{path}/{pkg}.git", "/a/b%3Fc/pkg.v1"},
+	} {
+		m := MustMatcher(TemplateMatcher(TemplateConfig{Import: tst.imp, Repo: tst.repo}))
+		u, err := url.Parse("https://example.com" + tst.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if repo, err := m.Match(u); err != ErrNotPackageURL {
+			t.Log(tst.path)
+			t.Fatalf("want ErrNotPackageURL, got %v %v\n", repo, err)
+		}
+	}
+
+	// Paths with no owner element do not match.
+	u, _ = url.Parse("https://example.com/pkg.v2")
+	if _, err := matcher.Match(u); err != ErrNotPackageURL {
+		t.Fatal("want ErrNotPackageURL, got", err)
+	}
+}
+
+var templateMatcherInvalidTests = []TemplateConfig{
+	{Import: "{path}/{pkg}", Repo: "https://git.corp/{pkg}"},
+	{Import: "{pkg}.v{major}.{pkg}", Repo: "https://git.corp/{pkg}"},
+	{Import: "{pkg}.v{major", Repo: "https://git.corp/{pkg}"},
+	{Import: "{p-k-g}.v{major}", Repo: "https://git.corp/{pkg}"},
+	{Import: "{subpath}/{pkg}.v{major}", Repo: "https://git.corp/{pkg}"},
+	{Import: "{name}/{pkg}.v{major}", Repo: "https://git.corp/{name}"},
+	{Import: "{version}/{pkg}.v{major}", Repo: "https://git.corp/{pkg}"},
+	{Import: "{ref}/{pkg}.v{major}", Repo: "https://git.corp/{pkg}"},
+	{Import: "{kind}/{pkg}.v{major}", Repo: "https://git.corp/{pkg}"},
+	{Import: "{pkg}.v{major}", Repo: "https://git.corp/{pkg}", Vars: map[string]string{"ref": "main"}},
+	{Import: "{pkg}.v{major}", Repo: ""},
+	{Import: "{pkg}.v{major}", Repo: "https://git.corp/{owner}/{pkg}"},
+	{Import: "{pkg}.v{major}", Repo: "https://git.corp/{pkg}{/dir}"},
+	{Import: "{pkg}.v{major}", Repo: "https://git.corp/{pkg}", File: "{file}#L{line"},
+}
+
+// Tests that invalid template matcher configurations are rejected.
+func TestTemplateMatcherInvalid(t *testing.T) {
+	for _, tst := range templateMatcherInvalidTests {
+		if _, err := TemplateMatcher(tst); err == nil {
+			t.Logf("%+v\n", tst)
+			t.Fatal("expected error")
+		}
+	}
+}
@@ -6,10 +6,15 @@ package semver
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
+	"strings"
 )
 
 // Repo defines a single repository and target version.
+//
+// The JSON encoding of a Repo is the one of all of its fields (see MarshalJSON)
+// rather than the one of the embedded Version.
 type Repo struct {
 	Version
 
@@ -39,6 +44,107 @@ type Repo struct {
 	//  https://github.com/golang/gddo/pull/212#issue-50104435
 	//
 	GoSource string
+
+	// GoSourceFunc, if non-nil, is called by the Handler to replace GoSource
+	// once the branch or tag of the repository has been chosen (see Ref). It is
+	// used by hosts whose source-view URLs depend on the chosen ref.
+	GoSourceFunc func(r *Repo) string
+
+	// Constraint, if non-nil, is used by the Handler to choose the branch or
+	// tag of the repository instead of the major version of Version: the
+	// branch or tag with the greatest version satisfying it is chosen.
+	Constraint *Constraint
+
+	// Subdir is the subdirectory of the repository holding the module of the
+	// package, for repositories holding multiple modules. For example "lint"
+	// for a module tagged "lint/v1.2.3". If set, the Handler only considers
+	// tags prefixed with the subdirectory (and master for v0), and serves the
+	// subdirectory as the fourth field of the go-import meta tag. The fourth
+	// field is only understood by Go 1.25 and later: older go commands reject
+	// the meta tag, so such modules require Go 1.25 or later.
+	Subdir string
+
+	// Default, if not the zero value, is the policy used by the Handler to
+	// choose the branch or tag of the repository, for import paths without a
+	// version. The Handler then sets Version to the version that was chosen.
+	Default DefaultVersion
+
+	// Fallback, if non-nil, is used by the Handler instead of this repo if the
+	// requested version does not exist in the repository. It is used to
+	// resolve ambiguous import paths, e.g. "example.com/pkg/v2" may be either
+	// major version 2 of the package, or the "v2" subpackage of version 1.
+	Fallback *Repo
+
+	// Ref is the full name of the branch or tag of the repository chosen by
+	// the Handler, e.g. "refs/tags/v1.2.3". It is empty until chosen.
+	Ref string
+
+	// The chosen ref, see Ref and PseudoVersion.
+	chosen *gitRef
+}
+
+// DefaultVersion is a policy for choosing the branch or tag of a repository
+// for import paths without a version (e.g. "example.com/pkg"), see
+// Repo.Default. The zero value is no policy: the Version of the Repo is used.
+type DefaultVersion struct {
+	latest bool
+	v0     bool
+	branch string
+}
+
+var (
+	// DefaultLatest chooses the greatest release of the greatest major
+	// version having any release (i.e. ignoring pre-release and unstable
+	// versions), or the master branch if there is no such version.
+	DefaultLatest = DefaultVersion{latest: true}
+
+	// DefaultV0 chooses the greatest v0 branch or tag, or the master branch
+	// if there is no such version, just like "pkg.v0" import paths.
+	DefaultV0 = DefaultVersion{v0: true}
+)
+
+// DefaultBranch returns a policy choosing the branch with the given name, e.g.
+// "main".
+func DefaultBranch(name string) DefaultVersion {
+	return DefaultVersion{branch: name}
+}
+
+// String returns a string representation of the policy, e.g. "latest", "v0" or
+// "branch main".
+func (d DefaultVersion) String() string {
+	switch {
+	case d.latest:
+		return "latest"
+	case d.v0:
+		return "v0"
+	case len(d.branch) > 0:
+		return "branch " + d.branch
+	}
+	return "none"
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, the text form
+// is the same as String.
+func (d DefaultVersion) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, the text
+// form is the same as String (e.g. "latest", "v0", "branch main" or "none").
+func (d *DefaultVersion) UnmarshalText(text []byte) error {
+	switch s := string(text); {
+	case s == "latest":
+		*d = DefaultLatest
+	case s == "v0":
+		*d = DefaultV0
+	case s == "none":
+		*d = DefaultVersion{}
+	case strings.HasPrefix(s, "branch ") && len(s) > len("branch "):
+		*d = DefaultBranch(strings.TrimPrefix(s, "branch "))
+	default:
+		return fmt.Errorf("invalid default version policy %q", s)
+	}
+	return nil
 }
 
 // Status represents a single status code returned by a Handler's attempt to
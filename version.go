@@ -6,52 +6,141 @@ package semver
 
 import (
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
 // Version represents a semantic version.
+//
+// The Minor and Patch version numbers are -1 when they are absent, that is the
+// version "v1" is Version{Major=1, Minor=-1, Patch=-1} while "v1.0.0" is
+// Version{Major=1, Minor=0, Patch=0}. See the Precision method.
 type Version struct {
 	Major, Minor, Patch int
 
 	// If true, then this is the unstable version.
 	Unstable bool
+
+	// PreRelease is the dot-separated list of pre-release identifiers without
+	// the leading dash, for example "rc.1" or "beta.3". It is empty for
+	// release versions and for unstable versions.
+	PreRelease string
+
+	// Metadata is the dot-separated build metadata without the leading plus
+	// sign, for example "20261017.sha.abc123". It is ignored when determining
+	// version precedence (see Less and Equal).
+	Metadata string
+}
+
+// PreReleaseIdentifiers returns the dot-separated pre-release identifiers of
+// this version, for example:
+//
+//  "v2.1.0-rc.1"   -> []string{"rc", "1"}
+//  "v2.1.0-beta.3" -> []string{"beta", "3"}
+//  "v2.1.0"        -> nil
+//
+func (v Version) PreReleaseIdentifiers() []string {
+	if len(v.PreRelease) == 0 {
+		return nil
+	}
+	return strings.Split(v.PreRelease, ".")
 }
 
 // String returns a string representation of this version, for example:
 //
-//  Version{Major=1, Minor=2, Patch=3}                -> "v1.2.3"
-//  Version{Major=1, Minor=2, Patch=3, Unstable=true} -> "v1.2.3-unstable"
+//  Version{Major=1, Minor=2, Patch=3}                    -> "v1.2.3"
+//  Version{Major=1, Minor=2, Patch=3, Unstable=true}     -> "v1.2.3-unstable"
+//  Version{Major=1, Minor=2, Patch=3, PreRelease="rc.1"} -> "v1.2.3-rc.1"
+//  Version{Major=1, Minor=2, Patch=3, Metadata="build.5"} -> "v1.2.3+build.5"
+//
+//  Version{Major=1, Minor=2, Patch=-1}                   -> "v1.2"
+//  Version{Major=1, Minor=2, Patch=-1, Unstable=true}    -> "v1.2-unstable"
+//
+//  Version{Major=1, Minor=-1, Patch=-1}                  -> "v1"
+//  Version{Major=1, Minor=-1, Patch=-1, Unstable=true}   -> "v1-unstable"
 //
-//  Version{Major=1, Minor=2, Patch=-1}                -> "v1.2"
-//  Version{Major=1, Minor=2, Patch=-1, Unstable=true} -> "v1.2-unstable"
+// Only the version numbers that are present are written (see Precision), zero
+// version numbers are written as-is:
 //
-//  Version{Major=1, Minor=-1, Patch=-1}                -> "v1"
-//  Version{Major=1, Minor=-1, Patch=-1, Unstable=true} -> "v1-unstable"
+//  Version{Major=0, Minor=-1, Patch=-1}                  -> "v0"
+//  Version{Major=1, Minor=0, Patch=-1}                   -> "v1.0"
+//  Version{Major=1, Minor=0, Patch=3}                    -> "v1.0.3"
 //
+// Thus the string form of a version returned by ParseVersion is exactly the
+// string it was parsed from (with lenient parsing, e.g. of leading zeros, the
+// string form is the canonical one instead).
+//
+// An invalid version (i.e. with a negative Major version) is written in a
+// debug form, like "Version{Major=-1, Minor=-1, Patch=-1, Unstable=false}".
 func (v Version) String() string {
 	var s string
-	if v.Major > 0 && v.Minor > 0 && v.Patch > 0 {
+	switch v.Precision() {
+	case 3:
 		s = fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
-	} else if v.Major > 0 && v.Minor > 0 {
+	case 2:
 		s = fmt.Sprintf("v%d.%d", v.Major, v.Minor)
-	} else if v.Major > 0 {
+	case 1:
 		s = fmt.Sprintf("v%d", v.Major)
-	} else {
+	default:
 		return fmt.Sprintf("Version{Major=%d, Minor=%d, Patch=%d, Unstable=%t}", v.Major, v.Minor, v.Patch, v.Unstable)
 	}
 	if v.Unstable {
-		return s + "-unstable"
+		s += "-unstable"
+	} else if len(v.PreRelease) > 0 {
+		s += "-" + v.PreRelease
+	}
+	if len(v.Metadata) > 0 {
+		s += "+" + v.Metadata
 	}
 	return s
 }
 
+// Precision returns the number of version numbers present in this version,
+// for example:
+//
+//  "v1"     -> 1
+//  "v1.0"   -> 2
+//  "v1.0.0" -> 3
+//
+// A version number is present if it and all version numbers before it are
+// not negative. The precision of an invalid version is zero.
+func (v Version) Precision() int {
+	switch {
+	case v.Major < 0:
+		return 0
+	case v.Minor < 0:
+		return 1
+	case v.Patch < 0:
+		return 2
+	}
+	return 3
+}
+
+// Equal tells if v and the other version have the same precedence, that is if
+// neither one is less than the other. Unlike the == operator, it ignores any
+// build metadata (e.g. v1.4.2+build.1 is equal to v1.4.2+build.2).
+func (v Version) Equal(other Version) bool {
+	v.Metadata = ""
+	other.Metadata = ""
+	return v == other
+}
+
 // Less tells if v is a lesser version than the other version.
 //
 // It follows semver specification (e.g. v1.200.300 is less than v2). A
 // unstable version is *always* less than a stable version (e.g. v3-unstable is
 // less than v2).
+//
+// See SpecOrdering for an ordering which does not treat unstable versions
+// specially.
+//
+// Pre-release versions follow the semver precedence rules: a pre-release
+// version is less than the associated release version (e.g. v2.1.0-rc.1 is
+// less than v2.1.0), but not less than any older release (e.g. v2.1.0-rc.1 is
+// greater than v2.0.9).
+//
+// Build metadata is ignored (e.g. v1.4.2+build.1 is not less than
+// v1.4.2+build.2, nor the other way around).
 func (v Version) Less(other Version) bool {
 	if v.Unstable && !other.Unstable {
 		return true
@@ -76,7 +165,217 @@ func (v Version) Less(other Version) bool {
 	} else if v.Patch > other.Patch {
 		return false
 	}
-	return false
+	return comparePreRelease(v.PreRelease, other.PreRelease) < 0
+}
+
+// Compare compares the two versions in the same order as Less does. It returns
+// -1 if a is less than b, +1 if b is less than a, and zero otherwise (i.e. if
+// the versions are equal, see Equal).
+func Compare(a, b Version) int {
+	if a.Less(b) {
+		return -1
+	} else if b.Less(a) {
+		return 1
+	}
+	return 0
+}
+
+// Ordering selects how versions are ordered when sorting or choosing the
+// greatest version.
+type Ordering int
+
+const (
+	// LegacyOrdering orders versions as Version.Less does: an unstable version
+	// is *always* less than a stable version (e.g. v3-unstable is less than
+	// v2). It is the default for backward compatibility.
+	LegacyOrdering Ordering = iota
+
+	// SpecOrdering orders versions following the semver precedence rules:
+	// first by major, minor and patch version, and only then is a pre-release
+	// (or unstable) version less than the release version. An unstable version
+	// is treated as having the pre-release "unstable", for example:
+	//
+	//  v2 < v3-unstable < v3
+	//  v3.0.0-beta < v3.0.0-unstable < v3.0.0
+	//
+	SpecOrdering
+)
+
+// Compare compares the two versions using this ordering. It returns -1 if a is
+// less than b, +1 if b is less than a, and zero otherwise.
+func (o Ordering) Compare(a, b Version) int {
+	if o != SpecOrdering {
+		return Compare(a, b)
+	}
+	switch {
+	case a.Major != b.Major:
+		return compareInt(a.Major, b.Major)
+	case a.Minor != b.Minor:
+		return compareInt(a.Minor, b.Minor)
+	case a.Patch != b.Patch:
+		return compareInt(a.Patch, b.Patch)
+	}
+	return comparePreRelease(a.preRelease(), b.preRelease())
+}
+
+// Less tells if a is less than b using this ordering.
+func (o Ordering) Less(a, b Version) bool {
+	return o.Compare(a, b) < 0
+}
+
+// String returns the name of the ordering, e.g. "SpecOrdering".
+func (o Ordering) String() string {
+	switch o {
+	case LegacyOrdering:
+		return "LegacyOrdering"
+	case SpecOrdering:
+		return "SpecOrdering"
+	}
+	return fmt.Sprintf("Ordering(%d)", int(o))
+}
+
+// preRelease returns the pre-release of the version for precedence purposes,
+// that is "unstable" for unstable versions.
+func (v Version) preRelease() string {
+	if v.Unstable {
+		return "unstable"
+	}
+	return v.PreRelease
+}
+
+// comparePrecedence compares the two versions according to the semver
+// precedence rules, returning -1 if a < b, +1 if a > b and zero if they are
+// equal. Unlike Less, absent version components are treated as zero (e.g.
+// v1.2 is equal to v1.2.0) and an unstable version is treated as having the
+// pre-release "unstable" (e.g. v3-unstable is greater than v2).
+func comparePrecedence(a, b Version) int {
+	switch {
+	case a.Major != b.Major:
+		return compareInt(a.Major, b.Major)
+	case precisionZero(a.Minor) != precisionZero(b.Minor):
+		return compareInt(precisionZero(a.Minor), precisionZero(b.Minor))
+	case precisionZero(a.Patch) != precisionZero(b.Patch):
+		return compareInt(precisionZero(a.Patch), precisionZero(b.Patch))
+	}
+	return comparePreRelease(a.preRelease(), b.preRelease())
+}
+
+// compareInt returns -1 if a < b, +1 if a > b and zero if they are equal.
+func compareInt(a, b int) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// precisionZero returns i, or zero if i is -1 (i.e. an absent version
+// component).
+func precisionZero(i int) int {
+	if i == -1 {
+		return 0
+	}
+	return i
+}
+
+// comparePreRelease compares the two dot-separated pre-release strings a and b
+// according to the semver precedence rules. It returns -1 if a < b, +1 if a > b
+// and zero if they are equal.
+//
+// An empty string (i.e. a release version) has greater precedence than any
+// pre-release. Otherwise identifiers are compared from left to right: numeric
+// identifiers are compared numerically, alphanumeric ones lexically in ASCII
+// sort order, and numeric identifiers always have lower precedence than
+// alphanumeric ones. If all identifiers are equal, the shorter list has lower
+// precedence.
+func comparePreRelease(a, b string) int {
+	if a == b {
+		return 0
+	} else if len(a) == 0 {
+		return 1
+	} else if len(b) == 0 {
+		return -1
+	}
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareIdentifier(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	if len(as) < len(bs) {
+		return -1
+	} else if len(as) > len(bs) {
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifier compares two single pre-release identifiers, see
+// comparePreRelease for details.
+func compareIdentifier(a, b string) int {
+	aNum, bNum := isNumeric(a), isNumeric(b)
+	switch {
+	case aNum && !bNum:
+		return -1
+	case !aNum && bNum:
+		return 1
+	case aNum && bNum:
+		// Numeric identifiers have no leading zeros, so the longer one is
+		// the greater one. This avoids any integer overflow.
+		if len(a) < len(b) {
+			return -1
+		} else if len(a) > len(b) {
+			return 1
+		}
+	}
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// isNumeric tells if s consists of only the ASCII digits 0-9.
+func isNumeric(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// checkIdentifiers checks that s is a valid dot-separated list of pre-release
+// or build metadata identifiers. Each identifier must be non-empty and consist
+// of only ASCII alphanumerics and hyphens. Unless leadingZeros is true, numeric
+// identifiers must not include leading zeros.
+//
+// If s is valid then pos=-1 is returned, otherwise pos is the byte offset into
+// s at which the problem was found and msg describes it.
+func checkIdentifiers(s string, leadingZeros bool) (pos int, msg string) {
+	start := 0
+	for _, ident := range strings.Split(s, ".") {
+		if len(ident) == 0 {
+			return start, "empty identifier"
+		}
+		for i := 0; i < len(ident); i++ {
+			c := ident[i]
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '-') {
+				return start + i, fmt.Sprintf("invalid character %q in identifier", c)
+			}
+		}
+		if !leadingZeros && isNumeric(ident) && len(ident) > 1 && ident[0] == '0' {
+			return start, "leading zero in numeric identifier"
+		}
+		start += len(ident) + 1
+	}
+	return -1, ""
 }
 
 // InvalidVersion represents a completely invalid version.
@@ -87,8 +386,39 @@ var InvalidVersion = Version{
 	Unstable: false,
 }
 
-// Matches strings like "1", "1.1", and "1.1.1".
-var vsRegexp = regexp.MustCompile(`^([0-9]+)[\.]?([0-9]*)[\.]?([0-9]*)`)
+// ParseError describes a version string that could not be parsed by
+// ParseVersionStrict, or a constraint that could not be parsed by
+// ParseConstraint.
+type ParseError struct {
+	// The version or constraint string that was being parsed.
+	Input string
+
+	// The byte offset into Input at which the problem was found.
+	Pos int
+
+	// A description of the problem, e.g. "leading zero in version number".
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing %q: %s at position %d", e.Input, e.Msg, e.Pos)
+}
+
+// ParseVersionStrict parses a version string in the same form as ParseVersion
+// does, but returns a *ParseError describing the problem for any string that
+// is not exactly in that form. Unlike ParseVersion, it rejects:
+//
+//  ""                      (empty input)
+//  "v1.2.3.4"              (trailing garbage)
+//  "v1x"                   (trailing garbage)
+//  "v01"                   (leading zeros)
+//  "v1.2."                 (missing version number)
+//  "v99999999999999999999" (overflow)
+//
+func ParseVersionStrict(vs string) (Version, error) {
+	return parseVersion(vs, true)
+}
 
 // ParseVersion parses a version string in the form of:
 //
@@ -98,42 +428,146 @@ var vsRegexp = regexp.MustCompile(`^([0-9]+)[\.]?([0-9]*)[\.]?([0-9]*)`)
 //  "v1-unstable"
 //  "v1.2-unstable"
 //  "v1.2.1-unstable"
+//  "v1.2.1-rc.1"
+//  "v1.2.1-beta.3"
+//  "v1.2.1+20261017.sha.abc123"
+//  "v1.2.1-rc.1+build.5"
+//
+// A "-unstable" suffix sets the Unstable field, any other dash-separated
+// suffix is stored as the PreRelease identifiers (which may contain further
+// dashes, e.g. "v1-rc-1" has the pre-release "rc-1"). A plus-separated suffix
+// is stored as the build Metadata.
 //
 // It returns InvalidVersion for strings not suffixed with "v", like:
 //
 //  "1"
 //  "1.2-unstable"
 //
+// Parsing is lenient: leading zeros of the version numbers are accepted (but
+// not of numeric pre-release identifiers, e.g. "v1-rc.01" is invalid), version
+// numbers out of range are clamped to the maximum int value, and anything
+// following the version numbers up to the pre-release or metadata suffix is
+// ignored (e.g. "v1.2.3.4" is parsed as "v1.2.3"). Use ParseVersionStrict to
+// reject such strings with a descriptive error instead.
+//
+// The string form of a leniently parsed version is always accepted by
+// ParseVersionStrict (and thus by UnmarshalText).
 func ParseVersion(vs string) Version {
-	if vs[0] != 'v' {
+	v, err := parseVersion(vs, false)
+	if err != nil {
 		return InvalidVersion
 	}
-	vs = vs[1:] // Strip prefixed v
+	return v
+}
 
-	// Split by the dash seperated suffix. We expect only one dash suffix, and
-	// if present it must be "unstable".
-	dashSplit := strings.Split(vs, "-")
-	if len(dashSplit) > 2 || len(dashSplit) == 2 && dashSplit[1] != "unstable" {
-		return InvalidVersion
+// parseVersion implements both ParseVersion and ParseVersionStrict, the strict
+// flag selects between the two.
+func parseVersion(vs string, strict bool) (Version, error) {
+	fail := func(pos int, msg string) (Version, error) {
+		return InvalidVersion, &ParseError{Input: vs, Pos: pos, Msg: msg}
+	}
+	if len(vs) == 0 {
+		return fail(0, "empty version string")
+	}
+	if vs[0] != 'v' {
+		return fail(0, `missing "v" prefix`)
 	}
 
-	// We now use regexp to match the last part of the version string, which
-	// e.g. looks like "1", "1.1", or "1.1.1".
+	// Parse up to three dot-separated version numbers, e.g. "1", "1.1", or
+	// "1.1.1".
 	var (
-		m = vsRegexp.FindStringSubmatch(dashSplit[0])
-		v = InvalidVersion
+		v   = InvalidVersion
+		pos = 1 // Strip prefixed v
 	)
-	if len(m) > 1 && len(m[1]) > 0 {
-		v.Major, _ = strconv.Atoi(m[1])
+	for n := 0; n < 3; n++ {
+		start := pos
+		for pos < len(vs) && '0' <= vs[pos] && vs[pos] <= '9' {
+			pos++
+		}
+		if pos == start {
+			if n == 0 || strict {
+				return fail(pos, "expected version number")
+			}
+			break
+		}
+		num := vs[start:pos]
+		if strict && len(num) > 1 && num[0] == '0' {
+			return fail(start, "leading zero in version number")
+		}
+		// In lenient mode, a version number out of range is clamped to the
+		// maximum int value (as returned by Atoi).
+		i, err := strconv.Atoi(num)
+		if err != nil && strict {
+			return fail(start, "version number out of range")
+		}
+		switch n {
+		case 0:
+			v.Major = i
+		case 1:
+			v.Minor = i
+		case 2:
+			v.Patch = i
+		}
+		if n == 2 || pos == len(vs) || vs[pos] != '.' {
+			break
+		}
+		pos++ // Skip the dot.
+	}
+
+	// Anything that follows must be the pre-release or metadata suffix. In
+	// lenient mode we skip over any trailing garbage up to that point.
+	if !strict {
+		for pos < len(vs) && vs[pos] != '-' && vs[pos] != '+' {
+			pos++
+		}
+	}
+	if i, msg := parseSuffix(vs, pos, &v); i != -1 {
+		return fail(i, msg)
 	}
-	if len(m) > 2 && len(m[2]) > 0 {
-		v.Minor, _ = strconv.Atoi(m[2])
+	return v, nil
+}
+
+// parseSuffix parses the pre-release and build metadata suffixes, like
+// "-rc.1+build.5", starting at vs[pos] into the given version. The string must
+// end after the suffixes.
+//
+// If the suffixes are valid then i=-1 is returned, otherwise i is the byte
+// offset into vs at which the problem was found and msg describes it.
+func parseSuffix(vs string, pos int, v *Version) (i int, msg string) {
+	if pos < len(vs) && vs[pos] != '-' && vs[pos] != '+' {
+		return pos, fmt.Sprintf("unexpected character %q", vs[pos])
 	}
-	if len(m) > 3 && len(m[3]) > 0 {
-		v.Patch, _ = strconv.Atoi(m[3])
+
+	// Everything after the first dash is the pre-release suffix which must be
+	// a valid list of identifiers.
+	if pos < len(vs) && vs[pos] == '-' {
+		pos++
+		end := strings.IndexByte(vs[pos:], '+')
+		if end == -1 {
+			end = len(vs)
+		} else {
+			end += pos
+		}
+		pre := vs[pos:end]
+		if i, msg := checkIdentifiers(pre, false); i != -1 {
+			return pos + i, msg
+		}
+		if pre == "unstable" {
+			v.Unstable = true
+		} else {
+			v.PreRelease = pre
+		}
+		pos = end
 	}
-	if v.Major != -1 && len(dashSplit) == 2 && dashSplit[1] == "unstable" {
-		v.Unstable = true
+
+	// Everything after the first plus sign is the build metadata which must be
+	// a valid list of identifiers.
+	if pos < len(vs) && vs[pos] == '+' {
+		pos++
+		if i, msg := checkIdentifiers(vs[pos:], true); i != -1 {
+			return pos + i, msg
+		}
+		v.Metadata = vs[pos:]
 	}
-	return v
+	return -1, ""
 }
@@ -4,7 +4,10 @@
 
 package semver
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 var versionLessTests = []struct {
 	a, b  Version
@@ -34,6 +37,46 @@ var versionLessTests = []struct {
 		a:     Version{Major: 1, Minor: 1, Patch: 1, Unstable: true},
 		b:     Version{Major: 1, Minor: 1, Patch: 1},
 		aLess: true,
+	}, {
+		a:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "rc.1"},
+		b:     Version{Major: 2, Minor: 1, Patch: 0},
+		aLess: true,
+	}, {
+		a:     Version{Major: 2, Minor: 1, Patch: 0},
+		b:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "rc.1"},
+		aLess: false,
+	}, {
+		a:     Version{Major: 2, Minor: 0, Patch: 9},
+		b:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "rc.1"},
+		aLess: true,
+	}, {
+		a:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "alpha"},
+		b:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "alpha.1"},
+		aLess: true,
+	}, {
+		a:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "alpha.1"},
+		b:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "alpha.beta"},
+		aLess: true,
+	}, {
+		a:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "beta.2"},
+		b:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "beta.11"},
+		aLess: true,
+	}, {
+		a:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "beta.11"},
+		b:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "rc.1"},
+		aLess: true,
+	}, {
+		a:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "rc.1"},
+		b:     Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "rc.1"},
+		aLess: false,
+	}, {
+		a:     Version{Major: 1, Minor: 4, Patch: 2, Metadata: "build.1"},
+		b:     Version{Major: 1, Minor: 4, Patch: 2, Metadata: "build.2"},
+		aLess: false,
+	}, {
+		a:     Version{Major: 1, Minor: 4, Patch: 2, Metadata: "build.2"},
+		b:     Version{Major: 1, Minor: 4, Patch: 2, Metadata: "build.1"},
+		aLess: false,
 	},
 }
 
@@ -59,6 +102,14 @@ var versionParseTests = []struct {
 	{v: "v100-unstable", exp: Version{Major: 100, Minor: -1, Patch: -1, Unstable: true}},
 	{v: "v1.24-unstable", exp: Version{Major: 1, Minor: 24, Patch: -1, Unstable: true}},
 	{v: "v14.2.34-unstable", exp: Version{Major: 14, Minor: 2, Patch: 34, Unstable: true}},
+	{v: "v2.1.0-rc.1", exp: Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "rc.1"}},
+	{v: "v2.1.0-beta.3", exp: Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "beta.3"}},
+	{v: "v1-foobar", exp: Version{Major: 1, Minor: -1, Patch: -1, PreRelease: "foobar"}},
+	{v: "v1-foo-bar", exp: Version{Major: 1, Minor: -1, Patch: -1, PreRelease: "foo-bar"}},
+	{v: "v1-unstable.1", exp: Version{Major: 1, Minor: -1, Patch: -1, PreRelease: "unstable.1"}},
+	{v: "v1.4.2+20261017.sha.abc123", exp: Version{Major: 1, Minor: 4, Patch: 2, Metadata: "20261017.sha.abc123"}},
+	{v: "v1.4.2-rc.1+build.007", exp: Version{Major: 1, Minor: 4, Patch: 2, PreRelease: "rc.1", Metadata: "build.007"}},
+	{v: "v1-unstable+exp-1", exp: Version{Major: 1, Minor: -1, Patch: -1, Unstable: true, Metadata: "exp-1"}},
 
 	// Version strings must have 'v' prefix.
 	{v: "1", exp: InvalidVersion},
@@ -70,8 +121,23 @@ var versionParseTests = []struct {
 
 	// Invalid version strings.
 	{v: "v-unstable", exp: InvalidVersion},
-	{v: "v1-foobar", exp: InvalidVersion},
-	{v: "v1-foo-bar", exp: InvalidVersion},
+	{v: "v1-", exp: InvalidVersion},
+	{v: "v1-rc..1", exp: InvalidVersion},
+	{v: "v1-rc.01", exp: InvalidVersion},
+	{v: "v1-rc_1", exp: InvalidVersion},
+	{v: "v1+", exp: InvalidVersion},
+	{v: "v1+build..1", exp: InvalidVersion},
+	{v: "v1+build+1", exp: InvalidVersion},
+	{v: "", exp: InvalidVersion},
+	{v: "v", exp: InvalidVersion},
+
+	// Lenient parsing of otherwise invalid version strings.
+	{v: "v1.2.3.4", exp: Version{Major: 1, Minor: 2, Patch: 3}},
+	{v: "v1x", exp: Version{Major: 1, Minor: -1, Patch: -1}},
+	{v: "v01", exp: Version{Major: 1, Minor: -1, Patch: -1}},
+	{v: "v1.", exp: Version{Major: 1, Minor: -1, Patch: -1}},
+	{v: "v1x-unstable", exp: Version{Major: 1, Minor: -1, Patch: -1, Unstable: true}},
+	{v: "v99999999999999999999", exp: Version{Major: math.MaxInt, Minor: -1, Patch: -1}},
 	{v: "ga.v1.r.3.ba.4.ge", exp: InvalidVersion},
 }
 
@@ -81,8 +147,70 @@ func TestVersionParsing(t *testing.T) {
 		want := tst.exp
 		if got != want {
 			t.Logf("%q\n", tst.v)
-			t.Logf("got Major=%d Minor=%d Patch=%d Unstable=%t\n", got.Major, got.Minor, got.Patch, got.Unstable)
-			t.Fatalf("want Major=%d Minor=%d Patch=%d Unstable=%t\n", want.Major, want.Minor, want.Patch, want.Unstable)
+			t.Logf("got Major=%d Minor=%d Patch=%d Unstable=%t PreRelease=%q Metadata=%q\n", got.Major, got.Minor, got.Patch, got.Unstable, got.PreRelease, got.Metadata)
+			t.Fatalf("want Major=%d Minor=%d Patch=%d Unstable=%t PreRelease=%q Metadata=%q\n", want.Major, want.Minor, want.Patch, want.Unstable, want.PreRelease, want.Metadata)
+		}
+	}
+}
+
+var versionParseStrictTests = []struct {
+	v   string
+	exp Version
+	pos int // Position of the error, or -1 if valid.
+}{
+	// Valid version strings.
+	{v: "v0", exp: Version{Major: 0, Minor: -1, Patch: -1}, pos: -1},
+	{v: "v1.0", exp: Version{Major: 1, Minor: 0, Patch: -1}, pos: -1},
+	{v: "v1.2.3", exp: Version{Major: 1, Minor: 2, Patch: 3}, pos: -1},
+	{v: "v10.20.30-unstable", exp: Version{Major: 10, Minor: 20, Patch: 30, Unstable: true}, pos: -1},
+	{v: "v2.1.0-rc.1+build.5", exp: Version{Major: 2, Minor: 1, Patch: 0, PreRelease: "rc.1", Metadata: "build.5"}, pos: -1},
+
+	// Invalid version strings.
+	{v: "", pos: 0},
+	{v: "1.2.3", pos: 0},
+	{v: "v", pos: 1},
+	{v: "vx", pos: 1},
+	{v: "v01", pos: 1},
+	{v: "v1.02", pos: 3},
+	{v: "v1.2.3.4", pos: 6},
+	{v: "v1x", pos: 2},
+	{v: "v1.", pos: 3},
+	{v: "v1.2.", pos: 5},
+	{v: "v99999999999999999999", pos: 1},
+	{v: "v1.2.3-", pos: 7},
+	{v: "v1.2.3-rc.01", pos: 10},
+	{v: "v1.2.3-rc..1", pos: 10},
+	{v: "v1.2.3-rc_1", pos: 9},
+	{v: "v1.2.3+", pos: 7},
+	{v: "v1.2.3+a.b!", pos: 10},
+}
+
+func TestVersionParsingStrict(t *testing.T) {
+	for _, tst := range versionParseStrictTests {
+		got, err := ParseVersionStrict(tst.v)
+		if tst.pos == -1 {
+			if err != nil {
+				t.Logf("%q\n", tst.v)
+				t.Fatal(err)
+			}
+			if got != tst.exp {
+				t.Logf("%q\n", tst.v)
+				t.Fatalf("got %#v want %#v\n", got, tst.exp)
+			}
+			continue
+		}
+		pErr, ok := err.(*ParseError)
+		if !ok {
+			t.Logf("%q\n", tst.v)
+			t.Fatalf("want *ParseError, got %#v\n", err)
+		}
+		if pErr.Pos != tst.pos || pErr.Input != tst.v {
+			t.Logf("%q\n", tst.v)
+			t.Fatalf("got error %q at %d, want position %d\n", pErr, pErr.Pos, tst.pos)
+		}
+		if got != InvalidVersion {
+			t.Logf("%q\n", tst.v)
+			t.Fatalf("got %#v want InvalidVersion\n", got)
 		}
 	}
 }
@@ -98,6 +226,17 @@ var versionStringTests = []struct {
 	{v: "v100-unstable", exp: Version{Major: 100, Minor: -1, Patch: -1, Unstable: true}},
 	{v: "v1.24-unstable", exp: Version{Major: 1, Minor: 24, Patch: -1, Unstable: true}},
 	{v: "v14.2.34-unstable", exp: Version{Major: 14, Minor: 2, Patch: 34, Unstable: true}},
+	{v: "v2.1.3-rc.1", exp: Version{Major: 2, Minor: 1, Patch: 3, PreRelease: "rc.1"}},
+	{v: "v2-beta.3", exp: Version{Major: 2, Minor: -1, Patch: -1, PreRelease: "beta.3"}},
+	{v: "v1.4.2+build.5", exp: Version{Major: 1, Minor: 4, Patch: 2, Metadata: "build.5"}},
+	{v: "v1.4-rc.1+build.5", exp: Version{Major: 1, Minor: 4, Patch: -1, PreRelease: "rc.1", Metadata: "build.5"}},
+	{v: "v0", exp: Version{Major: 0, Minor: -1, Patch: -1}},
+	{v: "v0.0.0", exp: Version{Major: 0, Minor: 0, Patch: 0}},
+	{v: "v1.0", exp: Version{Major: 1, Minor: 0, Patch: -1}},
+	{v: "v1.0.3", exp: Version{Major: 1, Minor: 0, Patch: 3}},
+	{v: "v0.1-unstable", exp: Version{Major: 0, Minor: 1, Patch: -1, Unstable: true}},
+	{v: "v1", exp: Version{Major: 1, Minor: -1, Patch: 3}},
+	{v: "Version{Major=-1, Minor=-1, Patch=-1, Unstable=false}", exp: InvalidVersion},
 }
 
 func TestVersionString(t *testing.T) {
@@ -110,3 +249,87 @@ func TestVersionString(t *testing.T) {
 		}
 	}
 }
+
+var versionEqualTests = []struct {
+	a, b  string
+	equal bool
+}{
+	{"v1.4.2", "v1.4.2", true},
+	{"v1.4.2+build.1", "v1.4.2+build.2", true},
+	{"v1.4.2+build.1", "v1.4.2", true},
+	{"v1.4.2-rc.1+build.1", "v1.4.2-rc.1+build.2", true},
+	{"v1.4.2-rc.1", "v1.4.2", false},
+	{"v1.4.2", "v1.4.3", false},
+	{"v1.4", "v1.4.0", false},
+}
+
+func TestVersionEqual(t *testing.T) {
+	for _, tst := range versionEqualTests {
+		a, b := ParseVersion(tst.a), ParseVersion(tst.b)
+		if got := a.Equal(b); got != tst.equal {
+			t.Fatalf("%q.Equal(%q) got %t want %t\n", tst.a, tst.b, got, tst.equal)
+		}
+	}
+}
+
+// Tests that versions round-trip exactly through ParseVersion and String.
+func TestVersionRoundTrip(t *testing.T) {
+	for _, vs := range []string{
+		"v0", "v0.0", "v0.0.0", "v1", "v1.0", "v1.0.0", "v1.0.3", "v0.3",
+		"v0-unstable", "v1.0-rc.1", "v0.0.0-20191109021931-daa7c04131f5",
+		"v2.0.0+build.1", "v10.0.1-beta.2+exp.sha.5114f85",
+	} {
+		if got := ParseVersion(vs).String(); got != vs {
+			t.Fatalf("got %q want %q\n", got, vs)
+		}
+	}
+}
+
+func TestVersionPrecision(t *testing.T) {
+	tests := []struct {
+		v         Version
+		precision int
+	}{
+		{ParseVersion("v0"), 1},
+		{ParseVersion("v1.0"), 2},
+		{ParseVersion("v1.0.0"), 3},
+		{ParseVersion("v1.0.0-rc.1"), 3},
+		{Version{Major: 1, Minor: -1, Patch: 3}, 1},
+		{Version{}, 3},
+		{InvalidVersion, 0},
+	}
+	for _, tst := range tests {
+		if got := tst.v.Precision(); got != tst.precision {
+			t.Fatalf("%#v.Precision() got %d want %d\n", tst.v, got, tst.precision)
+		}
+	}
+}
+
+var orderingTests = []struct {
+	a, b         string
+	legacy, spec int
+}{
+	{"v3-unstable", "v2", -1, 1},
+	{"v2", "v3-unstable", 1, -1},
+	{"v3-unstable", "v3", -1, -1},
+	{"v3.0.0-unstable", "v3.0.0-beta", -1, 1},
+	{"v3.0.0-rc.1", "v3.0.0", -1, -1},
+	{"v2.1.0-rc.1", "v2.0.9", 1, 1},
+	{"v1.4.2+build.1", "v1.4.2+build.2", 0, 0},
+	{"v1-unstable", "v1-unstable", 0, 0},
+}
+
+func TestOrdering(t *testing.T) {
+	for _, tst := range orderingTests {
+		a, b := ParseVersion(tst.a), ParseVersion(tst.b)
+		if got := LegacyOrdering.Compare(a, b); got != tst.legacy {
+			t.Fatalf("LegacyOrdering.Compare(%q, %q) got %d want %d\n", tst.a, tst.b, got, tst.legacy)
+		}
+		if got := SpecOrdering.Compare(a, b); got != tst.spec {
+			t.Fatalf("SpecOrdering.Compare(%q, %q) got %d want %d\n", tst.a, tst.b, got, tst.spec)
+		}
+		if got := SpecOrdering.Less(a, b); got != (tst.spec < 0) {
+			t.Fatalf("SpecOrdering.Less(%q, %q) got %t\n", tst.a, tst.b, got)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import "sort"
+
+// Versions is a list of versions. It implements the sort.Interface interface,
+// sorting the versions in ascending order as defined by Version.Less.
+type Versions []Version
+
+// Len implements the sort.Interface interface.
+func (vs Versions) Len() int { return len(vs) }
+
+// Swap implements the sort.Interface interface.
+func (vs Versions) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
+
+// Less implements the sort.Interface interface.
+func (vs Versions) Less(i, j int) bool { return vs[i].Less(vs[j]) }
+
+// SortBy sorts the versions in ascending order using the given ordering. The
+// sort is stable.
+func (vs Versions) SortBy(o Ordering) {
+	sort.Stable(orderedVersions{vs, o})
+}
+
+// orderedVersions implements sort.Interface using a specific ordering.
+type orderedVersions struct {
+	Versions
+	o Ordering
+}
+
+func (s orderedVersions) Less(i, j int) bool { return s.o.Less(s.Versions[i], s.Versions[j]) }
+
+// Max returns the greatest version in the list, as defined by Version.Less.
+// If the list is empty, ok=false is returned.
+func (vs Versions) Max() (max Version, ok bool) {
+	return vs.MaxBy(LegacyOrdering)
+}
+
+// MaxBy returns the greatest version in the list using the given ordering. If
+// the list is empty, ok=false is returned.
+func (vs Versions) MaxBy(o Ordering) (max Version, ok bool) {
+	for i, v := range vs {
+		if i == 0 || o.Less(max, v) {
+			max = v
+		}
+	}
+	return max, len(vs) > 0
+}
+
+// Filter returns a new list of the versions for which the given function
+// returns true, in their original order.
+func (vs Versions) Filter(f func(v Version) bool) Versions {
+	var filtered Versions
+	for _, v := range vs {
+		if f(v) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
+// Latest returns the version that a Handler using the given version scheme
+// would choose for an import path with the given major line (e.g.
+// "example.com/pkg.v2" for line=2): the greatest stable (i.e. not unstable)
+// version of that line, as compared by the scheme. Pre-release versions are
+// only returned if there is no release version of that line at all. For the
+// default scheme of a Handler, use SemVer{Ordering: h.Ordering}.
+//
+// If there is no such version, ok=false is returned.
+func (vs Versions) Latest(line int, s VersionScheme) (latest Version, ok bool) {
+	candidates := vs.Filter(func(v Version) bool {
+		return s.Line(v) == line && !v.Unstable
+	})
+	releases := candidates.Filter(func(v Version) bool {
+		return len(v.PreRelease) == 0
+	})
+	if len(releases) > 0 {
+		candidates = releases
+	}
+	for i, v := range candidates {
+		if i == 0 || s.Compare(latest, v) < 0 {
+			latest = v
+		}
+	}
+	return latest, len(candidates) > 0
+}
+
+// LatestStable returns the greatest version in the list which is neither an
+// unstable nor a pre-release version, regardless of its major version. If
+// there is no such version, ok=false is returned.
+func (vs Versions) LatestStable() (latest Version, ok bool) {
+	return vs.Filter(func(v Version) bool {
+		return !v.Unstable && len(v.PreRelease) == 0
+	}).Max()
+}
+
+// Dedup returns a new list of the versions with duplicates removed, keeping
+// the first occurrence of each, in their original order. Versions are
+// duplicates if they are equal as defined by Version.Equal, i.e. ignoring build
+// metadata.
+func (vs Versions) Dedup() Versions {
+	// Stably sort the indices of the versions, such that duplicates are
+	// adjacent with the first occurrence first, and keep the first of each.
+	order := make([]int, len(vs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return vs[order[i]].Less(vs[order[j]])
+	})
+	keep := make([]bool, len(vs))
+	for i, n := range order {
+		keep[n] = i == 0 || !vs[n].Equal(vs[order[i-1]])
+	}
+
+	var deduped Versions
+	for i, v := range vs {
+		if keep[i] {
+			deduped = append(deduped, v)
+		}
+	}
+	return deduped
+}
+
+// GroupByMajor groups the versions by their major version number. The
+// versions in each group are in their original order.
+func (vs Versions) GroupByMajor() map[int]Versions {
+	groups := make(map[int]Versions)
+	for _, v := range vs {
+		groups[v.Major] = append(groups[v.Major], v)
+	}
+	return groups
+}
@@ -0,0 +1,163 @@
+// Copyright 2014 The Azul3D Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func parseVersions(s string) Versions {
+	var vs Versions
+	for _, f := range strings.Fields(s) {
+		vs = append(vs, ParseVersion(f))
+	}
+	return vs
+}
+
+func TestVersionCompare(t *testing.T) {
+	for _, tst := range versionLessTests {
+		want := 0
+		if tst.aLess {
+			want = -1
+		} else if tst.b.Less(tst.a) {
+			want = 1
+		}
+		if got := Compare(tst.a, tst.b); got != want {
+			t.Log("a", tst.a)
+			t.Log("b", tst.b)
+			t.Fatalf("Compare(a, b) got %d want %d\n", got, want)
+		}
+	}
+}
+
+func TestVersionsSort(t *testing.T) {
+	vs := parseVersions("v2 v1.2.0 v1-unstable v1.10.0 v1.2.0-rc.1 v0.9 v3-unstable")
+	sort.Sort(vs)
+	want := parseVersions("v1-unstable v3-unstable v0.9 v1.2.0-rc.1 v1.2.0 v1.10.0 v2")
+	for i := range want {
+		if vs[i] != want[i] {
+			t.Fatalf("got %v want %v\n", vs, want)
+		}
+	}
+}
+
+// majorMinorScheme is a VersionScheme whose major lines are the minor version
+// numbers, and which orders versions in reverse.
+type majorMinorScheme struct{ SemVer }
+
+func (majorMinorScheme) Line(v Version) int { return v.Minor }
+
+func (majorMinorScheme) Compare(a, b Version) int { return Compare(b, a) }
+
+var versionsLatestTests = []struct {
+	versions string
+	scheme   VersionScheme
+	line     int
+	latest   string
+}{
+	{"v1 v1.2 v1.0.1 v2-unstable", SemVer{}, 1, "v1.2"},
+	{"v1 v1.2 v1.3.0-beta.1", SemVer{}, 1, "v1.2"},
+	{"v3.0.0-rc.1 v3.0.0-rc.2 v1.2", SemVer{}, 3, "v3.0.0-rc.2"},
+	{"v3.0.0-rc.1 v3.0.0-rc.2 v1.2", SemVer{Ordering: SpecOrdering}, 3, "v3.0.0-rc.2"},
+	{"v2-unstable v1", SemVer{}, 2, ""},
+	{"v1 v1.2", SemVer{}, 0, ""},
+	{"v2026.9.3 v2026.10.1 v2025.12.0", CalVer{}, 2026, "v2026.10.1"},
+	{"v1.2 v2.2.1 v3.2.0 v1.3", majorMinorScheme{}, 2, "v1.2"},
+}
+
+func TestVersionsLatest(t *testing.T) {
+	for _, tst := range versionsLatestTests {
+		latest, ok := parseVersions(tst.versions).Latest(tst.line, tst.scheme)
+		if ok != (len(tst.latest) > 0) || ok && latest != ParseVersion(tst.latest) {
+			t.Logf("%q line %d\n", tst.versions, tst.line)
+			t.Fatalf("got %v (ok=%t) want %q\n", latest, ok, tst.latest)
+		}
+	}
+}
+
+func TestVersionsLatestStable(t *testing.T) {
+	vs := parseVersions("v1.2 v3-unstable v2.0.0-rc.1 v1.10")
+	latest, ok := vs.LatestStable()
+	if !ok || latest != ParseVersion("v1.10") {
+		t.Fatalf("got %v (ok=%t) want v1.10\n", latest, ok)
+	}
+	if _, ok := parseVersions("v3-unstable v2.0.0-rc.1").LatestStable(); ok {
+		t.Fatal("want ok=false for no stable versions")
+	}
+	if _, ok := Versions(nil).LatestStable(); ok {
+		t.Fatal("want ok=false for empty list")
+	}
+}
+
+func TestVersionsFilter(t *testing.T) {
+	vs := parseVersions("v1.2 v3-unstable v2.0.0 v1.10")
+	got := vs.Filter(func(v Version) bool { return v.Major == 1 })
+	want := parseVersions("v1.2 v1.10")
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("got %v want %v\n", got, want)
+	}
+}
+
+func TestVersionsDedup(t *testing.T) {
+	vs := parseVersions("v2 v1.2.0+b.1 v1.2 v1.2.0 v1.2.0+b.2 v2 v1.2 v0.1")
+	got := vs.Dedup()
+	want := parseVersions("v2 v1.2.0+b.1 v1.2 v0.1")
+	if len(got) != len(want) {
+		t.Fatalf("got %v want %v\n", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v want %v\n", got, want)
+		}
+	}
+}
+
+func TestVersionsGroupByMajor(t *testing.T) {
+	groups := parseVersions("v1.2 v2 v1.3 v0.1 v2.1-unstable").GroupByMajor()
+	if len(groups) != 3 {
+		t.Fatalf("got %d groups want 3\n", len(groups))
+	}
+	for major, want := range map[int]string{0: "v0.1", 1: "v1.2 v1.3", 2: "v2 v2.1-unstable"} {
+		got := groups[major]
+		w := parseVersions(want)
+		if len(got) != len(w) {
+			t.Fatalf("major %d: got %v want %v\n", major, got, w)
+		}
+		for i := range w {
+			if got[i] != w[i] {
+				t.Fatalf("major %d: got %v want %v\n", major, got, w)
+			}
+		}
+	}
+}
+
+func TestVersionsSortBy(t *testing.T) {
+	vs := parseVersions("v2 v1.2.0 v1-unstable v3-unstable v1.2.0-rc.1 v3")
+	vs.SortBy(SpecOrdering)
+	want := parseVersions("v1-unstable v1.2.0-rc.1 v1.2.0 v2 v3-unstable v3")
+	for i := range want {
+		if vs[i] != want[i] {
+			t.Fatalf("got %v want %v\n", vs, want)
+		}
+	}
+	vs.SortBy(LegacyOrdering)
+	want = parseVersions("v1-unstable v3-unstable v1.2.0-rc.1 v1.2.0 v2 v3")
+	for i := range want {
+		if vs[i] != want[i] {
+			t.Fatalf("got %v want %v\n", vs, want)
+		}
+	}
+
+	max, _ := parseVersions("v2 v3-unstable").MaxBy(SpecOrdering)
+	if max != ParseVersion("v3-unstable") {
+		t.Fatalf("MaxBy(SpecOrdering) got %v want v3-unstable\n", max)
+	}
+	max, _ = parseVersions("v2 v3-unstable").Max()
+	if max != ParseVersion("v2") {
+		t.Fatalf("Max() got %v want v2\n", max)
+	}
+}